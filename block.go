@@ -1,73 +0,0 @@
-package main
-
-import (
-	"bytes"
-	"encoding/gob"
-	"log"
-	"time"
-)
-
-// Block the block of blockchain
-type Block struct {
-	Timestamp     int64
-	Transactions  []*Transaction
-	PrevBlockHash []byte
-	Hash          []byte
-	Nonce         int
-}
-
-// NewBlock create a new block
-func NewBlock(transactions []*Transaction, prevBlockHash []byte) *Block {
-	block := &Block{time.Now().Unix(), transactions,
-		prevBlockHash, []byte{}, 0}
-	pow := NewProofOfWork(block)
-	nonce, hash := pow.Run()
-	block.Hash = hash[:]
-	block.Nonce = nonce
-
-	return block
-}
-
-// NewGenesisBlock create a genesis block
-// a genesis block is the first block of a blockchain
-func NewGenesisBlock(coinbase *Transaction) *Block {
-	return NewBlock([]*Transaction{coinbase}, []byte{})
-}
-
-// Serialize serialize block to bytes
-func (b *Block) Serialize() []byte {
-	var result bytes.Buffer
-
-	encoder := gob.NewEncoder(&result)
-	err := encoder.Encode(b)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	return result.Bytes()
-}
-
-// DeserializeBlock deserialize bytes to block
-func DeserializeBlock(d []byte) *Block {
-	var b Block
-	decoder := gob.NewDecoder(bytes.NewReader(d))
-
-	err := decoder.Decode(&b)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return &b
-}
-
-// HashTransactions ...
-func (b *Block) HashTransactions() []byte {
-	var txHashes [][]byte
-
-	for _, tx := range b.Transactions {
-		txHashes = append(txHashes, tx.Hash())
-	}
-
-	mTree := NewMarkleTree(txHashes)
-
-	return mTree.RootNode.Data
-}
@@ -0,0 +1,137 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// Block the block of blockchain
+type Block struct {
+	Timestamp     int64
+	Transactions  []*Transaction
+	PrevBlockHash []byte
+	Hash          []byte
+	Nonce         int
+	Height        int
+}
+
+// NewBlock create a new block and mine it with the given difficulty
+func NewBlock(transactions []*Transaction, prevBlockHash []byte, height, targetBits int) *Block {
+	block := &Block{time.Now().Unix(), transactions,
+		prevBlockHash, []byte{}, 0, height}
+	block.mine(targetBits)
+
+	return block
+}
+
+// NewGenesisBlock create a genesis block
+// a genesis block is the first block of a blockchain
+func NewGenesisBlock(coinbase *Transaction, params *chaincfg.Params) *Block {
+	block := &Block{params.GenesisTimestamp, []*Transaction{coinbase},
+		[]byte{}, []byte{}, 0, 0}
+	block.mine(params.TargetBits)
+
+	return block
+}
+
+// GenesisBlock returns the fixed genesis block of a network, it returns
+// ErrGenesisMismatch if the parameters do not hash to its GenesisHash
+func GenesisBlock(params *chaincfg.Params) (*Block, error) {
+	if len(params.GenesisHash) == 0 {
+		return nil, fmt.Errorf("%s has no fixed genesis block", params.Name)
+	}
+	pubKeyHash, err := hex.DecodeString(params.GenesisPubKeyHash)
+	if err != nil {
+		return nil, fmt.Errorf("genesis public key hash of %s: %v", params.Name, err)
+	}
+
+	out := TxOutput{params.Emission.Subsidy(0), pubKeyHash, wallet.KeyP256}
+	coinbase := newCoinbase(0, params.GenesisCoinbaseData, out)
+	block := &Block{params.GenesisTimestamp, []*Transaction{coinbase},
+		[]byte{}, []byte{}, params.GenesisNonce, 0}
+	pow := NewProofOfWork(block, params.TargetBits)
+	hash := sha256.Sum256(pow.prepartData(block.Nonce))
+	block.Hash = hash[:]
+
+	if hex.EncodeToString(block.Hash) != params.GenesisHash || !pow.Validate() {
+		return nil, fmt.Errorf("%w: the genesis block of %s hashes to %x", ErrGenesisMismatch, params.Name, block.Hash)
+	}
+
+	return block, nil
+}
+
+func (b *Block) mine(targetBits int) {
+	pow := NewProofOfWork(b, targetBits)
+	nonce, hash := pow.Run()
+	b.Hash = hash[:]
+	b.Nonce = nonce
+}
+
+// Serialize serialize block to bytes
+func (b *Block) Serialize() []byte {
+	var result bytes.Buffer
+
+	encoder := gob.NewEncoder(&result)
+	err := encoder.Encode(b)
+	if err != nil {
+		// a block holds no types gob cannot encode
+		panic(err)
+	}
+
+	return result.Bytes()
+}
+
+// DeserializeBlock deserialize bytes to block
+func DeserializeBlock(d []byte) (*Block, error) {
+	var b Block
+	decoder := gob.NewDecoder(bytes.NewReader(d))
+
+	err := decoder.Decode(&b)
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// HashTransactions ...
+func (b *Block) HashTransactions() []byte {
+	var txHashes [][]byte
+
+	for _, tx := range b.Transactions {
+		txHashes = append(txHashes, tx.Hash())
+	}
+
+	mTree := NewMarkleTree(txHashes)
+
+	return mTree.RootNode.Data
+}
+
+// HashWitnesses returns the witness commitment of the block: the merkle
+// root of the witness hashes of its transactions. The proof of work covers
+// it next to the merkle root of the transaction IDs, which leave the
+// signatures out.
+func (b *Block) HashWitnesses() []byte {
+	var wtxHashes [][]byte
+
+	for _, tx := range b.Transactions {
+		wtxHashes = append(wtxHashes, tx.WitnessHash())
+	}
+	mTree := NewMarkleTree(wtxHashes)
+
+	return mTree.RootNode.Data
+}
+
+// header returns a copy of the block without its transactions
+func (b *Block) header() *Block {
+	h := *b
+	h.Transactions = nil
+
+	return &h
+}
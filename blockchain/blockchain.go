@@ -0,0 +1,619 @@
+// Package blockchain implements the blocks, transactions and UTXO set of the chain
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+const (
+	utxoBucket    = "utxoBucket"
+	blocksBucket  = "blocksBucket"
+	txIndexBucket = "txIndexBucket"
+	undoBucket    = "undoBucket"
+)
+
+// Blockchain the blockchain
+type Blockchain struct {
+	store  Store
+	tip    []byte
+	params *chaincfg.Params
+	// utxo caches the UTXO set of the store, when nil it is used directly
+	utxo *utxoCache
+	// sigs remembers the signatures verified, when nil every one is checked
+	sigs *sigCache
+}
+
+func newBlockchain(store Store, tip []byte, params *chaincfg.Params) *Blockchain {
+	cache := newUTXOCache(DefaultUTXOCacheSize)
+	if p, ok := store.(pruner); ok {
+		cache.maxLag = p.pruneDepth()
+	}
+
+	return &Blockchain{store, tip, params, cache, newSigCache(DefaultSigCacheSize)}
+}
+
+// SetUTXOCacheSize sets the memory budget of the UTXO cache in bytes,
+// with 0 the changes are written to the store after every block
+func (bc *Blockchain) SetUTXOCacheSize(budget int) {
+	bc.utxo.mu.Lock()
+	defer bc.utxo.mu.Unlock()
+
+	bc.utxo.budget = budget
+}
+
+// view runs fn in a read-only store transaction, which sees the UTXO set
+// through the cache
+func (bc *Blockchain) view(fn func(tx StoreTx) error) error {
+	if bc.utxo == nil {
+		return bc.store.View(fn)
+	}
+
+	return bc.store.View(func(tx StoreTx) error {
+		return fn(cachedStoreTx{tx, newCacheTx(bc.utxo, tx.UTXO())})
+	})
+}
+
+// update runs fn in a store transaction, its changes to the UTXO set go to
+// the cache once the transaction commits
+func (bc *Blockchain) update(fn func(tx StoreTx) error) error {
+	if bc.utxo == nil {
+		return bc.store.Update(fn)
+	}
+
+	var cached *cacheTx
+	err := bc.store.Update(func(tx StoreTx) error {
+		cached = newCacheTx(bc.utxo, tx.UTXO())
+		return fn(cachedStoreTx{tx, cached})
+	})
+	if err != nil {
+		return err
+	}
+	bc.utxo.commit(cached)
+
+	return nil
+}
+
+// Flush writes the changes kept in the UTXO cache to the store
+func (bc *Blockchain) Flush() error {
+	if bc.utxo == nil {
+		return nil
+	}
+
+	return bc.utxo.flush(bc.store)
+}
+
+// flushIfNeeded flushes the UTXO cache when it is over its budget or too
+// far ahead of a pruning store, it is called at block boundaries
+func (bc *Blockchain) flushIfNeeded() error {
+	if bc.utxo == nil || !bc.utxo.needsFlush() {
+		return nil
+	}
+
+	return bc.Flush()
+}
+
+// BlockchainIterator the iterator of a blockchain
+type BlockchainIterator struct {
+	currentHash []byte
+	store       Store
+}
+
+// AddBlock mine a block of the given transactions on top of the chain.
+// The block, the new tip and the changes to the UTXO set and the
+// transaction index are written in a single store transaction.
+func (bc *Blockchain) AddBlock(trans []*Transaction) (*Block, error) {
+	height, err := bc.GetBestHeight()
+	if err != nil {
+		return nil, err
+	}
+	height++
+
+	err = bc.checkTransactions(trans, height)
+	if err != nil {
+		return nil, err
+	}
+
+	newBlock := NewBlock(trans, bc.tip, height, bc.params.TargetBits)
+	err = bc.connect(newBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	return newBlock, nil
+}
+
+// connect connects a block on top of the chain
+func (bc *Blockchain) connect(block *Block) error {
+	err := bc.update(func(tx StoreTx) error {
+		return connectBlock(tx, block)
+	})
+	if err != nil {
+		return err
+	}
+	bc.tip = block.Hash
+
+	return bc.flushIfNeeded()
+}
+
+// checkTransactions verifies the transactions of a block at height
+// before it is connected. The first transaction, and only it, is the
+// coinbase, which may pay the subsidy and the fees.
+func (bc *Blockchain) checkTransactions(trans []*Transaction, height int) error {
+	if len(trans) == 0 || !trans[0].IsCoinbase() {
+		return fmt.Errorf("%w: the block does not start with a coinbase", ErrInvalidCoinbase)
+	}
+
+	var checks []sigCheck
+	fees := 0
+	reward := 0
+	for i, tx := range trans {
+		if i > 0 && tx.IsCoinbase() {
+			return fmt.Errorf("%w: transaction %d of the block is a second coinbase", ErrInvalidCoinbase, i)
+		}
+		if !bytes.Equal(tx.ID, tx.Hash()) {
+			return fmt.Errorf("transaction %x: %w", tx.ID, ErrTxIDMismatch)
+		}
+		prevOuts, err := bc.findPrevOuts(tx)
+		if err != nil {
+			return err
+		}
+		fee, err := tx.Fee(prevOuts)
+		if err != nil {
+			return err
+		}
+		fees += fee
+		if tx.IsCoinbase() {
+			reward += tx.OutputValue()
+		}
+
+		txChecks, err := tx.sigChecks(prevOuts)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, txChecks...)
+	}
+	if reward > bc.params.Emission.Subsidy(height)+fees {
+		return fmt.Errorf("%w: it pays more than the block subsidy and fees", ErrInvalidCoinbase)
+	}
+
+	// the inputs of all transactions are verified together
+	return verifySignatures(checks, bc.sigs, 0)
+}
+
+// connectBlock stores a block on top of the chain and applies it to the
+// UTXO set and the transaction index. The proof of work does not cover the
+// height of the block, it must be the one above the tip's.
+func connectBlock(tx StoreTx, block *Block) error {
+	tip := tx.State().Tip()
+	if !bytes.Equal(block.PrevBlockHash, tip) {
+		return fmt.Errorf("block %x does not extend the tip %x", block.Hash, tip)
+	}
+	height := 0
+	if tip != nil {
+		parent, err := tx.Blocks().Header(tip)
+		if err != nil {
+			return err
+		}
+		height = parent.Height + 1
+	}
+	if block.Height != height {
+		return fmt.Errorf("block %x at height %d, want %d: %w", block.Hash, block.Height, height, ErrInvalidHeight)
+	}
+
+	err := tx.Blocks().PutBlock(block)
+	if err != nil {
+		return err
+	}
+	err = applyBlock(tx, block)
+	if err != nil {
+		return err
+	}
+
+	return tx.State().SetTip(block.Hash)
+}
+
+// DisconnectTip removes the tip block from the chain, the outputs it spent
+// are restored to the UTXO set from its undo data. It is the first step of
+// a reorganization, the block itself stays in the store.
+func (bc *Blockchain) DisconnectTip() (*Block, error) {
+	var block *Block
+
+	err := bc.update(func(tx StoreTx) error {
+		var err error
+		block, err = tx.Blocks().Block(bc.tip)
+		if err != nil {
+			return err
+		}
+		if len(block.PrevBlockHash) == 0 {
+			return errors.New("cannot disconnect the genesis block")
+		}
+		spent, err := tx.Blocks().Undo(block.Hash)
+		if err != nil {
+			return err
+		}
+
+		s := tx.UTXO()
+		for _, t := range block.Transactions {
+			for index := range t.Vout {
+				err := s.DeleteOutput(OutPoint{t.ID, index})
+				if err != nil {
+					return err
+				}
+			}
+			err := tx.TxIndex().Delete(t.ID)
+			if err != nil {
+				return err
+			}
+		}
+		for _, so := range spent {
+			err := s.PutOutput(so.OutPoint, so.Output)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = s.SetBestBlock(block.PrevBlockHash, block.Height-1)
+		if err != nil {
+			return err
+		}
+
+		return tx.State().SetTip(block.PrevBlockHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	bc.tip = block.PrevBlockHash
+
+	return block, bc.flushIfNeeded()
+}
+
+// NewBlockchain opens the chain kept in the store,
+// it returns ErrNoBlockchain if there is none and ErrUTXOMismatch
+// if the UTXO set is not up to date with the tip
+func NewBlockchain(store Store, params *chaincfg.Params) (*Blockchain, error) {
+	var tip []byte
+
+	err := store.View(func(tx StoreTx) error {
+		tip = tx.State().Tip()
+		if tip == nil {
+			return ErrNoBlockchain
+		}
+		err := checkGenesis(tx, params)
+		if err != nil {
+			return err
+		}
+
+		best, _ := tx.UTXO().BestBlock()
+		if !bytes.Equal(best, tip) {
+			return fmt.Errorf("%w: UTXO set is at %x, tip is %x", ErrUTXOMismatch, best, tip)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newBlockchain(store, tip, params), nil
+}
+
+// checkGenesis returns ErrGenesisMismatch if the store does not hold the
+// fixed genesis block of the network. A chain loaded from a UTXO snapshot
+// gets it once the snapshot is validated.
+func checkGenesis(tx StoreTx, params *chaincfg.Params) error {
+	if len(params.GenesisHash) == 0 || tx.State().SnapshotBase() != nil {
+		return nil
+	}
+	hash, err := hex.DecodeString(params.GenesisHash)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Blocks().Header(hash)
+	if errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("%w: the chain does not start from block %s of %s", ErrGenesisMismatch, params.GenesisHash, params.Name)
+	}
+
+	return err
+}
+
+// ReindexBlockchain opens the chain kept in the store and brings its
+// UTXO set and transaction index up to date, it repairs a store
+// NewBlockchain returned ErrUTXOMismatch for
+func ReindexBlockchain(store Store, params *chaincfg.Params, opts ReindexOptions) (*Blockchain, error) {
+	var tip []byte
+
+	err := store.View(func(tx StoreTx) error {
+		tip = tx.State().Tip()
+		if tip == nil {
+			return ErrNoBlockchain
+		}
+		return checkGenesis(tx, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bc := newBlockchain(store, tip, params)
+	err = UTxOSet{bc}.Reindex(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return bc, nil
+}
+
+// CreateBlockchain creates a chain in the store. It starts from the fixed
+// genesis block of the network, on a private network from a genesis block
+// rewarding the given address.
+func CreateBlockchain(store Store, address string, params *chaincfg.Params) (*Blockchain, error) {
+	var genesis *Block
+	if len(params.GenesisHash) != 0 {
+		var err error
+		genesis, err = GenesisBlock(params)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		cbTX, err := NewCoinbaseTX(address, params.GenesisCoinbaseData, 0, 0, params)
+		if err != nil {
+			return nil, err
+		}
+		genesis = NewGenesisBlock(cbTX, params)
+	}
+
+	err := store.Update(func(tx StoreTx) error {
+		if tx.State().Tip() != nil {
+			return errors.New("blockchain already exists")
+		}
+
+		return connectBlock(tx, genesis)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newBlockchain(store, genesis.Hash, params), nil
+}
+
+// Close flushes the UTXO cache and releases the store of the chain
+func (bc *Blockchain) Close() error {
+	err := bc.Flush()
+	if err != nil {
+		bc.store.Close()
+		return err
+	}
+
+	return bc.store.Close()
+}
+
+// Params returns the parameters of the chain's network
+func (bc *Blockchain) Params() *chaincfg.Params {
+	return bc.params
+}
+
+// GetBestHeight returns the height of the tip block
+func (bc *Blockchain) GetBestHeight() (int, error) {
+	lastBlock, err := bc.Iterator().NextHeader()
+	if err != nil {
+		return 0, err
+	}
+
+	return lastBlock.Height, nil
+}
+
+// IssuedSupply walks the whole chain and sums the coins the coinbase
+// outputs of every block create, what they pay beyond the fees of the
+// block. A block paying more than its scheduled subsidy is an error.
+func (bc *Blockchain) IssuedSupply() (int, error) {
+	issued := 0
+	iter := bc.Iterator()
+
+	for {
+		block, err := iter.Next()
+		if err != nil {
+			return issued, err
+		}
+
+		fees, err := bc.blockFees(block)
+		if err != nil {
+			return issued, err
+		}
+		reward := 0
+		for _, tx := range block.Transactions {
+			if tx.IsCoinbase() {
+				reward += tx.OutputValue()
+			}
+		}
+		created := reward - fees
+		subsidy := bc.params.Emission.Subsidy(block.Height)
+		if created > subsidy {
+			return issued, fmt.Errorf("block %x at height %d pays %d, subsidy is %d",
+				block.Hash, block.Height, created, subsidy)
+		}
+		issued += created
+
+		if len(block.PrevBlockHash) == 0 {
+			break
+		}
+	}
+
+	return issued, nil
+}
+
+// blockFees returns the fees the transactions of a connected block pay,
+// the outputs they spend are the undo data of the block
+func (bc *Blockchain) blockFees(block *Block) (int, error) {
+	var spent []SpentOutput
+	err := bc.store.View(func(tx StoreTx) error {
+		var err error
+		spent, err = tx.Blocks().Undo(block.Hash)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	fees := 0
+	for _, s := range spent {
+		fees += s.Output.Value
+	}
+	for _, tx := range block.Transactions {
+		if !tx.IsCoinbase() {
+			fees -= tx.OutputValue()
+		}
+	}
+
+	return fees, nil
+}
+
+// TransactionFee returns the fee of a transaction spending outputs of the
+// UTXO set, which the coinbase of the block holding it may claim
+func (bc *Blockchain) TransactionFee(tx *Transaction) (int, error) {
+	prevOuts, err := bc.findPrevOuts(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return tx.Fee(prevOuts)
+}
+
+// FindTransaction looks the transaction up in the transaction index
+func (bc *Blockchain) FindTransaction(id []byte) (Transaction, error) {
+	var found *Transaction
+
+	err := bc.store.View(func(tx StoreTx) error {
+		hash, err := tx.TxIndex().BlockHash(id)
+		if err != nil {
+			return err
+		}
+		block, err := tx.Blocks().Block(hash)
+		if err != nil {
+			return fmt.Errorf("transaction %x: %w", id, err)
+		}
+
+		for _, t := range block.Transactions {
+			if bytes.Equal(t.ID, id) {
+				found = t
+				return nil
+			}
+		}
+		return fmt.Errorf("transaction %x is indexed in block %x which does not hold it", id, hash)
+	})
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	return *found, nil
+}
+
+// findPrevOuts looks up the outputs the inputs of the transaction spend
+// in the UTXO set, it returns ErrMissingInput if one is spent or unknown
+func (bc *Blockchain) findPrevOuts(t *Transaction) (map[string]TxOutput, error) {
+	prevOuts := make(map[string]TxOutput)
+
+	if t.IsCoinbase() {
+		return prevOuts, nil
+	}
+
+	err := bc.view(func(tx StoreTx) error {
+		for _, in := range t.Vin {
+			op := in.OutPoint()
+			out, err := tx.UTXO().Output(op)
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("transaction %x spends %s: %w", t.ID, op, ErrMissingInput)
+			}
+			if err != nil {
+				return err
+			}
+			prevOuts[op.String()] = out
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prevOuts, nil
+}
+
+// SignTransaction signs every input of the transaction with the spender,
+// a wallet or a redeem script with the wallets of its keys, and the hash type
+func (bc *Blockchain) SignTransaction(tx *Transaction, s wallet.Spender, hashType SigHashType) error {
+	prevOuts, err := bc.findPrevOuts(tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Sign(s, prevOuts, hashType)
+}
+
+// VerifyTransaction checks the signatures of the transaction's inputs
+// against the outputs they spend in the UTXO set. The valid signatures
+// are remembered, so they are not checked again when a block holds it.
+func (bc *Blockchain) VerifyTransaction(tx *Transaction) error {
+	checks, err := bc.sigChecks(tx)
+	if err != nil {
+		return err
+	}
+
+	return verifySignatures(checks, bc.sigs, 0)
+}
+
+// sigChecks returns the signature checks of the transaction's inputs
+func (bc *Blockchain) sigChecks(tx *Transaction) ([]sigCheck, error) {
+	prevOuts, err := bc.findPrevOuts(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.sigChecks(prevOuts)
+}
+
+// Iterator get a iterator of a block chain
+func (bc *Blockchain) Iterator() *BlockchainIterator {
+	return &BlockchainIterator{bc.tip, bc.store}
+}
+
+// CurrentHash returns the hash of the block Next will return,
+// it is empty once the genesis block has been returned
+func (bci *BlockchainIterator) CurrentHash() []byte {
+	return bci.currentHash
+}
+
+// Next get next block of block chain, it returns ErrPruned without moving
+// on if the transactions of the block have been pruned
+func (bci *BlockchainIterator) Next() (*Block, error) {
+	var block *Block
+	err := bci.store.View(func(tx StoreTx) error {
+		var err error
+		block, err = tx.Blocks().Block(bci.currentHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	bci.currentHash = block.PrevBlockHash
+	return block, nil
+}
+
+// NextHeader returns the next block without its transactions,
+// headers are kept when blocks are pruned
+func (bci *BlockchainIterator) NextHeader() (*Block, error) {
+	var block *Block
+	err := bci.store.View(func(tx StoreTx) error {
+		var err error
+		block, err = tx.Blocks().Header(bci.currentHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	bci.currentHash = block.PrevBlockHash
+	return block, nil
+}
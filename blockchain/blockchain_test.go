@@ -0,0 +1,601 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func TestSinVerify(t *testing.T) {
+	params := privateParams()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc, err := CreateBlockchain(NewMemoryStore(), string(from.Address(params)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+
+	tx, err := NewUTXOTransaction(from, string(to.Address(params)), 10, &UTxOSet{bc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Fatal("verify failed: ", err)
+	}
+}
+
+func TestWitnessHash(t *testing.T) {
+	bc, tx := spendCoinbases(t, 1)
+	defer bc.Close()
+	params := bc.params
+
+	if !bytes.Equal(tx.ID, tx.Hash()) {
+		t.Fatal("signing changed the transaction ID")
+	}
+	if bytes.Equal(tx.Hash(), tx.WitnessHash()) {
+		t.Fatal("the witness hash leaves the signatures out")
+	}
+
+	// the high-S twin of the signature is valid ECDSA, re-encoding the
+	// signature that way changes the witness hash only
+	malleated := *tx
+	malleated.Vin = append([]TxInput{}, tx.Vin...)
+	sig := append([]byte{}, tx.Vin[0].Signature...)
+	s := new(big.Int).SetBytes(sig[32:64])
+	new(big.Int).Sub(elliptic.P256().Params().N, s).FillBytes(sig[32:64])
+	malleated.Vin[0].Signature = sig
+	if !bytes.Equal(malleated.Hash(), tx.ID) {
+		t.Error("re-encoding the signature changed the transaction ID")
+	}
+	if bytes.Equal(malleated.WitnessHash(), tx.WitnessHash()) {
+		t.Error("re-encoding the signature kept the witness hash")
+	}
+
+	// the proof of work commits to the witnesses
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(string(to.Address(params)), "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(cb.Hash(), cb.WitnessHash()) {
+		t.Error("coinbase witness hash is not its ID")
+	}
+	block := NewBlock([]*Transaction{cb, tx}, bc.tip, 1, params.TargetBits)
+	swapped := *block
+	swapped.Transactions = []*Transaction{cb, &malleated}
+	if !bytes.Equal(swapped.HashTransactions(), block.HashTransactions()) {
+		t.Error("the transaction IDs of the blocks differ")
+	}
+	if bytes.Equal(swapped.HashWitnesses(), block.HashWitnesses()) || NewProofOfWork(&swapped, params.TargetBits).Validate() {
+		t.Error("the block commits to the signatures it was mined with")
+	}
+
+	forged := *tx
+	forged.ID = cb.ID
+	if err := bc.checkTransactions([]*Transaction{cb, &forged}, 1); !errors.Is(err, ErrTxIDMismatch) {
+		t.Errorf("transaction with another ID: %v", err)
+	}
+}
+
+func TestConnectBlock(t *testing.T) {
+	params := privateParams()
+	store := NewMemoryStore()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromAddr, toAddr := string(from.Address(params)), string(to.Address(params))
+
+	bc, err := CreateBlockchain(store, fromAddr, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := UTxOSet{bc}
+
+	spend, err := NewUTXOTransaction(from, toAddr, 10, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(fromAddr, "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, spend}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the spent output is gone, spending it again fails and leaves no block
+	cb, err = NewCoinbaseTX(fromAddr, "", 2, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tip := bc.tip
+	if _, err := bc.AddBlock([]*Transaction{cb, spend}); !errors.Is(err, ErrMissingInput) {
+		t.Fatalf("double spend: %v", err)
+	}
+	if !bytes.Equal(bc.tip, tip) {
+		t.Fatal("tip moved by a failed block")
+	}
+
+	// the proof of work does not cover the height, which a block could
+	// otherwise pick for the subsidy of another one
+	for _, height := range []int{1, 3, 210000} {
+		block := NewBlock([]*Transaction{cb}, bc.tip, height, params.TargetBits)
+		if err := bc.connect(block); !errors.Is(err, ErrInvalidHeight) {
+			t.Errorf("block at height %d on top of height 1: %v", height, err)
+		}
+	}
+
+	balance := func(w *wallet.Wallet) int {
+		outs, err := utxo.FindUTXO(wallet.HashPublicKey(w.PublicKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := 0
+		for _, out := range outs {
+			total += out.Value
+		}
+		return total
+	}
+	if got := balance(from); got != 90 {
+		t.Errorf("sender balance = %d, want 90", got)
+	}
+	if got := balance(to); got != 10 {
+		t.Errorf("recipient balance = %d, want 10", got)
+	}
+
+	// a UTXO set behind the tip is detected and rebuilt by a reindex
+	store.Update(func(tx StoreTx) error {
+		return tx.UTXO().Clear()
+	})
+	if _, err := NewBlockchain(store, params); !errors.Is(err, ErrUTXOMismatch) {
+		t.Fatalf("open with a stale UTXO set: %v", err)
+	}
+	bc, err = ReindexBlockchain(store, params, ReindexOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo = UTxOSet{bc}
+	if got := balance(from); got != 90 {
+		t.Errorf("sender balance after reindex = %d, want 90", got)
+	}
+	if _, err := NewBlockchain(store, params); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBlockCoinbase(t *testing.T) {
+	params := privateParams()
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.Address(params))
+
+	bc, err := CreateBlockchain(NewMemoryStore(), address, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	spend, err := NewUTXOTransaction(w, address, 10, &UTxOSet{bc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	coinbase := func(data string, value int) *Transaction {
+		cb, err := NewCoinbaseTX(address, data, 1, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cb.Vout[0].Value = value
+		cb.ID = cb.Hash()
+		return cb
+	}
+	subsidy := params.Emission.Subsidy(1)
+	cb := coinbase("", subsidy)
+
+	// two coinbases sharing the subsidy pay no more than one would
+	for name, trans := range map[string][]*Transaction{
+		"no transaction":               nil,
+		"no coinbase":                  {spend},
+		"coinbase after a transaction": {spend, cb},
+		"two coinbases":                {coinbase("a", subsidy/2), coinbase("b", subsidy/2)},
+	} {
+		if _, err := bc.AddBlock(trans); !errors.Is(err, ErrInvalidCoinbase) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if _, err := bc.AddBlock([]*Transaction{cb, spend}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDisconnectTip(t *testing.T) {
+	params := privateParams()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromAddr := string(from.Address(params))
+
+	bc, err := CreateBlockchain(NewMemoryStore(), fromAddr, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := UTxOSet{bc}
+	before, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spend, err := NewUTXOTransaction(from, string(to.Address(params)), 10, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(fromAddr, "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := bc.AddBlock([]*Transaction{cb, spend})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disconnected, err := bc.DisconnectTip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(disconnected.Hash, block.Hash) {
+		t.Errorf("disconnected %x, want %x", disconnected.Hash, block.Hash)
+	}
+	after, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after.Hash, before.Hash) || !bytes.Equal(after.BestBlock, before.BestBlock) {
+		t.Errorf("UTXO set after disconnect = %+v, want %+v", after, before)
+	}
+	if _, err := bc.FindTransaction(spend.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("disconnected transaction: %v", err)
+	}
+
+	// the spent output is back, so the transaction can be mined again
+	if _, err := bc.AddBlock([]*Transaction{cb, spend}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.DisconnectTip(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.DisconnectTip(); err == nil {
+		t.Error("disconnected the genesis block")
+	}
+}
+
+func TestReindexResume(t *testing.T) {
+	params := privateParams()
+	store := NewMemoryStore()
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.Address(params))
+
+	bc, err := CreateBlockchain(store, address, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for h := 1; h <= 24; h++ {
+		cb, err := NewCoinbaseTX(address, "", h, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	utxo := UTxOSet{bc}
+	want, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// interrupted after the first batch
+	interrupt := errors.New("interrupted")
+	err = utxo.Reindex(ReindexOptions{
+		Full:      true,
+		BatchSize: 10,
+		Progress: func(height, tip int) error {
+			if height != 9 || tip != 24 {
+				t.Errorf("progress %d/%d, want 9/24", height, tip)
+			}
+			return interrupt
+		},
+	})
+	if err != interrupt {
+		t.Fatalf("reindex returned %v", err)
+	}
+	if _, err := NewBlockchain(store, params); !errors.Is(err, ErrUTXOMismatch) {
+		t.Fatalf("open during a reindex: %v", err)
+	}
+
+	var heights []int
+	bc, err = ReindexBlockchain(store, params, ReindexOptions{
+		BatchSize: 10,
+		Progress: func(height, tip int) error {
+			heights = append(heights, height)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heights) != 2 || heights[0] != 19 || heights[1] != 24 {
+		t.Errorf("resumed at heights %v, want [19 24]", heights)
+	}
+
+	got, err := UTxOSet{bc}.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Hash, want.Hash) || !bytes.Equal(got.BestBlock, want.BestBlock) {
+		t.Errorf("reindexed UTXO set = %+v, want %+v", got, want)
+	}
+}
+
+func TestKeyTypes(t *testing.T) {
+	params := privateParams()
+
+	var wallets []*wallet.Wallet
+	for _, keyType := range []wallet.KeyType{wallet.KeySchnorr, wallet.KeySecp256k1, wallet.KeyP256} {
+		w, err := wallet.NewWalletWithKeyType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallets = append(wallets, w)
+	}
+	address := func(w *wallet.Wallet) string {
+		return string(w.Address(params))
+	}
+
+	bc, err := CreateBlockchain(NewMemoryStore(), address(wallets[0]), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := UTxOSet{bc}
+	before, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// schnorr pays secp256k1, which pays p256
+	for i, w := range wallets[:2] {
+		tx, err := NewUTXOTransaction(w, address(wallets[i+1]), 20-5*i, &utxo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Vout[0].KeyType != wallets[i+1].KeyType || tx.Vout[1].KeyType != w.KeyType {
+			t.Errorf("outputs of types %s and %s", tx.Vout[0].KeyType, tx.Vout[1].KeyType)
+		}
+		cb, err := NewCoinbaseTX(address(wallets[2]), "", i+1, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb, tx}); err != nil {
+			t.Fatalf("%s: %v", w.KeyType, err)
+		}
+	}
+
+	outs, err := utxo.FindUTXO(wallet.HashPublicKey(wallets[1].PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outs) != 1 || outs[0].Value != 5 || outs[0].KeyType != wallet.KeySecp256k1 {
+		t.Errorf("secp256k1 outputs %+v", outs)
+	}
+
+	// the key types are restored from the undo data
+	for i := 0; i < 2; i++ {
+		if _, err := bc.DisconnectTip(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(after.Hash, before.Hash) {
+		t.Errorf("UTXO set after disconnect = %+v, want %+v", after, before)
+	}
+}
+
+func TestCoinControl(t *testing.T) {
+	params := privateParams()
+
+	var wallets []*wallet.Wallet
+	for i := 0; i < 4; i++ {
+		w, err := wallet.NewWallet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallets = append(wallets, w)
+	}
+	from, a, b, change := wallets[0], wallets[1], wallets[2], wallets[3]
+	address := func(w *wallet.Wallet) string {
+		return string(w.Address(params))
+	}
+
+	// the sender gets three coinbase outputs, the recipient a one
+	bc, err := CreateBlockchain(NewMemoryStore(), address(from), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	utxo := UTxOSet{bc}
+	for h, w := range []*wallet.Wallet{from, from, a} {
+		cb, err := NewCoinbaseTX(address(w), "", h+1, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unspent, err := utxo.ListUnspent(wallet.HashPublicKey(from.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unspent) != 3 {
+		t.Fatalf("%d outputs of the sender", len(unspent))
+	}
+	others, err := utxo.ListUnspent(wallet.HashPublicKey(a.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subsidy := unspent[0].Output.Value
+	payments := []Payment{{address(a), 10}, {address(b), 15}}
+
+	// the locked outputs are left alone
+	locked := map[string]bool{unspent[0].OutPoint.String(): true, unspent[1].OutPoint.String(): true}
+	tx, err := NewSendTransaction(from, payments, &CoinControl{Locked: locked, Change: address(change)}, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Vin) != 1 || !bytes.Equal(tx.Vin[0].Txid, unspent[2].OutPoint.Txid) {
+		t.Errorf("spent %s", tx)
+	}
+	if len(tx.Vout) != 3 || tx.Vout[2].Value != subsidy-25 || !bytes.Equal(tx.Vout[2].PubKeyHash, wallet.HashPublicKey(change.PublicKey)) {
+		t.Errorf("paid %s", tx)
+	}
+	if _, err := NewUnsignedTransaction(from.KeyType, wallet.HashPublicKey(from.PublicKey), []Payment{{address(a), 2 * subsidy}}, &CoinControl{Locked: locked}, &utxo); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("spent locked outputs: %v", err)
+	}
+
+	// only the inputs chosen are spent
+	inputs := []OutPoint{unspent[0].OutPoint, unspent[1].OutPoint}
+	chosen, err := NewUnsignedTransaction(from.KeyType, wallet.HashPublicKey(from.PublicKey), payments, &CoinControl{Inputs: inputs}, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chosen.Vin) != 2 || chosen.Vout[2].Value != 2*subsidy-25 {
+		t.Errorf("chosen inputs %s", chosen)
+	}
+	for _, cc := range []*CoinControl{
+		{Inputs: inputs, Locked: locked},
+		{Inputs: []OutPoint{others[0].OutPoint}},
+		{Inputs: []OutPoint{{tx.ID, 0}}},
+	} {
+		if _, err := NewUnsignedTransaction(from.KeyType, wallet.HashPublicKey(from.PublicKey), payments, cc, &utxo); err == nil {
+			t.Errorf("spent inputs %v", cc.Inputs)
+		}
+	}
+
+	cb, err := NewCoinbaseTX(address(from), "", 4, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, tx}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct {
+		w     *wallet.Wallet
+		value int
+	}{{a, subsidy + 10}, {b, 15}, {change, subsidy - 25}} {
+		outs, err := utxo.FindUTXO(wallet.HashPublicKey(want.w.PublicKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		value := 0
+		for _, out := range outs {
+			value += out.Value
+		}
+		if value != want.value {
+			t.Errorf("balance %d, want %d", value, want.value)
+		}
+	}
+
+	// a lock can only name an unspent output
+	if _, err := utxo.Output(unspent[2].OutPoint); !errors.Is(err, ErrNotFound) {
+		t.Errorf("spent output: %v", err)
+	}
+	if out, err := utxo.Output(unspent[0].OutPoint); err != nil || out.Value != subsidy {
+		t.Errorf("unspent output %v: %v", out, err)
+	}
+}
+
+func TestGenesisBlock(t *testing.T) {
+	for _, params := range chaincfg.Networks {
+		bc, err := CreateBlockchain(NewMemoryStore(), "", params)
+		if err != nil {
+			t.Fatalf("%s: %v", params.Name, err)
+		}
+		genesis := genesisBlock(t, bc)
+		if got := hex.EncodeToString(genesis.Hash); got != params.GenesisHash {
+			t.Errorf("%s: genesis block %s, want %s", params.Name, got, params.GenesisHash)
+		}
+		if _, err := NewBlockchain(bc.store, params); err != nil {
+			t.Errorf("%s: %v", params.Name, err)
+		}
+		bc.Close()
+
+		tampered := *params
+		tampered.GenesisCoinbaseData += "!"
+		if _, err := GenesisBlock(&tampered); !errors.Is(err, ErrGenesisMismatch) {
+			t.Errorf("%s: tampered genesis block: %v", params.Name, err)
+		}
+	}
+
+	// a private chain is not on the regtest network
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := privateParams()
+	bc, err := CreateBlockchain(NewMemoryStore(), string(w.Address(private)), private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	if _, err := NewBlockchain(bc.store, &chaincfg.RegTestParams); !errors.Is(err, ErrGenesisMismatch) {
+		t.Errorf("private chain opened on regtest: %v", err)
+	}
+}
+
+// privateParams returns the regtest parameters of a private network, the
+// genesis block of the chains the tests create pays one of their wallets
+func privateParams() *chaincfg.Params {
+	params := chaincfg.RegTestParams
+	params.GenesisHash = ""
+
+	return &params
+}
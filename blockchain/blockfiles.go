@@ -0,0 +1,324 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxBlockFileSize the size after which a new block file is started
+const maxBlockFileSize = 128 << 20
+
+// blockRecordMagic starts every record of a block file
+var blockRecordMagic = []byte{0xf9, 0xbe, 0xb4, 0xd9}
+
+const blockRecordHeaderLen = 8
+
+var errCorruptBlockFile = errors.New("corrupt block file")
+
+// blockStatus the state of a block body in the block files
+type blockStatus byte
+
+const (
+	blockStored blockStatus = iota + 1
+	// blockPruned the block file holding the body has been removed
+	blockPruned
+)
+
+// blockLocation the index entry of a block: where its body is stored
+type blockLocation struct {
+	File   uint32
+	Offset uint32
+	Length uint32
+	Height uint32
+	Status blockStatus
+}
+
+const blockLocationLen = 17
+
+func (l blockLocation) encode() []byte {
+	d := make([]byte, blockLocationLen)
+	binary.BigEndian.PutUint32(d[0:], l.File)
+	binary.BigEndian.PutUint32(d[4:], l.Offset)
+	binary.BigEndian.PutUint32(d[8:], l.Length)
+	binary.BigEndian.PutUint32(d[12:], l.Height)
+	d[16] = byte(l.Status)
+
+	return d
+}
+
+func decodeBlockLocation(d []byte) (blockLocation, error) {
+	if len(d) != blockLocationLen {
+		return blockLocation{}, errors.New("malformed block index entry")
+	}
+
+	return blockLocation{
+		File:   binary.BigEndian.Uint32(d[0:]),
+		Offset: binary.BigEndian.Uint32(d[4:]),
+		Length: binary.BigEndian.Uint32(d[8:]),
+		Height: binary.BigEndian.Uint32(d[12:]),
+		Status: blockStatus(d[16]),
+	}, nil
+}
+
+// blockFilesPos the end of the data written to the block files
+type blockFilesPos struct {
+	File uint32
+	Size uint32
+}
+
+func (p blockFilesPos) encode() []byte {
+	d := make([]byte, 8)
+	binary.BigEndian.PutUint32(d[0:], p.File)
+	binary.BigEndian.PutUint32(d[4:], p.Size)
+
+	return d
+}
+
+func decodeBlockFilesPos(d []byte) blockFilesPos {
+	if len(d) != 8 {
+		return blockFilesPos{}
+	}
+
+	return blockFilesPos{binary.BigEndian.Uint32(d[0:]), binary.BigEndian.Uint32(d[4:])}
+}
+
+// blockFileInfo the index entry of a block file
+type blockFileInfo struct {
+	Size      uint32
+	MaxHeight uint32
+	Pruned    bool
+}
+
+const blockFileInfoLen = 9
+
+func (i blockFileInfo) encode() []byte {
+	d := make([]byte, blockFileInfoLen)
+	binary.BigEndian.PutUint32(d[0:], i.Size)
+	binary.BigEndian.PutUint32(d[4:], i.MaxHeight)
+	if i.Pruned {
+		d[8] = 1
+	}
+
+	return d
+}
+
+func decodeBlockFileInfo(d []byte) (blockFileInfo, error) {
+	if len(d) != blockFileInfoLen {
+		return blockFileInfo{}, errors.New("malformed block file info")
+	}
+
+	return blockFileInfo{
+		Size:      binary.BigEndian.Uint32(d[0:]),
+		MaxHeight: binary.BigEndian.Uint32(d[4:]),
+		Pruned:    d[8] == 1,
+	}, nil
+}
+
+// blockFiles appends raw blocks to rotating blk*.dat files
+type blockFiles struct {
+	dir     string
+	maxSize uint32
+
+	mu   sync.Mutex
+	pos  blockFilesPos
+	file *os.File
+	// pruned the files to remove once the transaction pruning them commits
+	pruned []uint32
+}
+
+// openBlockFiles opens the block files of dir, pos is the end of the data
+// the index knows about. Anything written after it did not make it into
+// the index before a crash and is cut off.
+func openBlockFiles(dir string, maxSize uint32, pos blockFilesPos) (*blockFiles, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &blockFiles{dir: dir, maxSize: maxSize, pos: pos}
+	err = f.truncate(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *blockFiles) path(n uint32) string {
+	return filepath.Join(f.dir, fmt.Sprintf("blk%05d.dat", n))
+}
+
+// position returns the end of the data written so far
+func (f *blockFiles) position() blockFilesPos {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.pos
+}
+
+// append writes a block to the current file, starting a new one when it
+// is full. The data is synced before the location is returned.
+func (f *blockFiles) append(data []byte, height int) (blockLocation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	recordLen := uint32(blockRecordHeaderLen + len(data))
+	if f.pos.Size > 0 && f.pos.Size+recordLen > f.maxSize {
+		err := f.closeFile()
+		if err != nil {
+			return blockLocation{}, err
+		}
+		f.pos = blockFilesPos{f.pos.File + 1, 0}
+	}
+
+	if f.file == nil {
+		file, err := os.OpenFile(f.path(f.pos.File), os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return blockLocation{}, err
+		}
+		f.file = file
+	}
+
+	record := make([]byte, recordLen)
+	copy(record, blockRecordMagic)
+	binary.BigEndian.PutUint32(record[4:], uint32(len(data)))
+	copy(record[blockRecordHeaderLen:], data)
+
+	_, err := f.file.WriteAt(record, int64(f.pos.Size))
+	if err != nil {
+		return blockLocation{}, err
+	}
+	err = f.file.Sync()
+	if err != nil {
+		return blockLocation{}, err
+	}
+
+	loc := blockLocation{
+		File:   f.pos.File,
+		Offset: f.pos.Size,
+		Length: uint32(len(data)),
+		Height: uint32(height),
+		Status: blockStored,
+	}
+	f.pos.Size += recordLen
+
+	return loc, nil
+}
+
+// read returns the block stored at the location
+func (f *blockFiles) read(loc blockLocation) ([]byte, error) {
+	file, err := os.Open(f.path(loc.File))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	record := make([]byte, blockRecordHeaderLen+int(loc.Length))
+	_, err = file.ReadAt(record, int64(loc.Offset))
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: %s is truncated", errCorruptBlockFile, f.path(loc.File))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(record[:4], blockRecordMagic) ||
+		binary.BigEndian.Uint32(record[4:8]) != loc.Length {
+		return nil, fmt.Errorf("%w: bad record at %s:%d", errCorruptBlockFile, f.path(loc.File), loc.Offset)
+	}
+
+	return record[blockRecordHeaderLen:], nil
+}
+
+// truncate cuts the files back to pos, it undoes the appends of a
+// transaction that did not commit
+func (f *blockFiles) truncate(pos blockFilesPos) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := f.closeFile()
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(f.path(pos.File))
+	switch {
+	case os.IsNotExist(err) && pos.Size == 0:
+	case err != nil:
+		return err
+	case info.Size() < int64(pos.Size):
+		return fmt.Errorf("%w: %s is shorter than its index", errCorruptBlockFile, f.path(pos.File))
+	case info.Size() > int64(pos.Size):
+		err = os.Truncate(f.path(pos.File), int64(pos.Size))
+		if err != nil {
+			return err
+		}
+	}
+
+	// files started after pos
+	for n := pos.File + 1; ; n++ {
+		err := os.Remove(f.path(n))
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	f.pos = pos
+	return nil
+}
+
+// prune schedules the removal of a file, see removePruned
+func (f *blockFiles) prune(n uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pruned = append(f.pruned, n)
+}
+
+// removePruned removes the files pruned by a committed transaction,
+// or forgets about them if it did not commit
+func (f *blockFiles) removePruned(committed bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pruned := f.pruned
+	f.pruned = nil
+	if !committed {
+		return nil
+	}
+
+	for _, n := range pruned {
+		err := os.Remove(f.path(n))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *blockFiles) closeFile() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+
+	return err
+}
+
+func (f *blockFiles) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.closeFile()
+}
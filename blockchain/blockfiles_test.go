@@ -0,0 +1,337 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func TestFlatFileStore(t *testing.T) {
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "block-chain.db")
+	blocks := filepath.Join(dir, "blocks")
+
+	open := func() Store {
+		// small files so that a few blocks rotate them
+		store, err := openFlatFileStore(dbFile, blocks, 600, pruneConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	}
+	put := func(store Store, height int) error {
+		return store.Update(func(tx StoreTx) error {
+			block := &Block{Hash: []byte(fmt.Sprint("block", height)), Height: height}
+			return tx.Blocks().PutBlock(block)
+		})
+	}
+	check := func(store Store, height int) {
+		t.Helper()
+		err := store.View(func(tx StoreTx) error {
+			block, err := tx.Blocks().Block([]byte(fmt.Sprint("block", height)))
+			if err != nil {
+				return err
+			}
+			if block.Height != height {
+				t.Errorf("block height = %d, want %d", block.Height, height)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("block %d: %v", height, err)
+		}
+	}
+
+	store := open()
+	for h := 0; h < 5; h++ {
+		if err := put(store, h); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a failed update leaves its block out of the files
+	files := store.(*boltStore).files
+	pos := files.position()
+	failure := errors.New("failure")
+	err := store.Update(func(tx StoreTx) error {
+		tx.Blocks().PutBlock(&Block{Hash: []byte("failed"), Height: 99})
+		return failure
+	})
+	if err != failure {
+		t.Fatalf("update returned %v", err)
+	}
+	if files.position() != pos {
+		t.Errorf("position after rollback = %v, want %v", files.position(), pos)
+	}
+	if pos.File == 0 {
+		t.Error("block files were not rotated")
+	}
+
+	// a failed update waiting for a slow one does not roll back its block
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := store.Update(func(tx StoreTx) error {
+			time.Sleep(50 * time.Millisecond)
+			return tx.Blocks().PutBlock(&Block{Hash: []byte("block100"), Height: 100})
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	store.Update(func(tx StoreTx) error {
+		tx.Blocks().PutBlock(&Block{Hash: []byte("failed"), Height: 99})
+		return failure
+	})
+	wg.Wait()
+	check(store, 100)
+	pos = files.position()
+	store.Close()
+
+	// a crash between writing a block and committing the index
+	f, err := os.OpenFile(files.path(pos.File), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("partial block"))
+	f.Close()
+	os.WriteFile(files.path(pos.File+1), []byte("orphan"), 0600)
+
+	store = open()
+	defer store.Close()
+	if got := store.(*boltStore).files.position(); got != pos {
+		t.Errorf("position after reopen = %v, want %v", got, pos)
+	}
+	if _, err := os.Stat(files.path(pos.File + 1)); !os.IsNotExist(err) {
+		t.Error("orphan block file was kept")
+	}
+	if err := put(store, 5); err != nil {
+		t.Fatal(err)
+	}
+	for h := 0; h <= 5; h++ {
+		check(store, h)
+	}
+}
+
+func TestPruneBlockFiles(t *testing.T) {
+	params := privateParams()
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "block-chain.db")
+	blocks := filepath.Join(dir, "blocks")
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.Address(params))
+
+	store, err := openFlatFileStore(dbFile, blocks, 1000, pruneConfig{target: 3000, depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := CreateBlockchain(store, address, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hashes [][]byte
+	for h := 1; h <= 30; h++ {
+		cb, err := NewCoinbaseTX(address, "", h, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, err := bc.AddBlock([]*Transaction{cb})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, block.Hash)
+	}
+	tip, err := bc.Iterator().NextHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(blocks, "blk*.dat"))
+	var size int64
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	if size > 3000+1000 {
+		t.Errorf("block files take %d bytes after pruning", size)
+	}
+
+	err = store.View(func(tx StoreTx) error {
+		if _, err := tx.Blocks().Block(hashes[0]); !errors.Is(err, ErrPruned) {
+			t.Errorf("old block: %v", err)
+		}
+		if header, err := tx.Blocks().Header(hashes[0]); err != nil || header.Height != 1 {
+			t.Errorf("header of a pruned block: %v", err)
+		}
+		if _, err := tx.Blocks().Undo(hashes[0]); !errors.Is(err, ErrNotFound) {
+			t.Errorf("undo data of a pruned block: %v", err)
+		}
+		for _, hash := range hashes[len(hashes)-5:] {
+			if _, err := tx.Blocks().Block(hash); err != nil {
+				t.Errorf("recent block: %v", err)
+			}
+			if _, err := tx.Blocks().Undo(hash); err != nil {
+				t.Errorf("undo data of a recent block: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iter := bc.Iterator()
+	for len(iter.CurrentHash()) != 0 {
+		_, err := iter.Next()
+		if errors.Is(err, ErrPruned) {
+			_, err = iter.NextHeader()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tip.Height != 30 {
+		t.Errorf("tip header height = %d", tip.Height)
+	}
+	if _, err := bc.IssuedSupply(); !errors.Is(err, ErrPruned) {
+		t.Errorf("supply of a pruned chain: %v", err)
+	}
+	if err := (UTxOSet{bc}).Reindex(ReindexOptions{Full: true}); !errors.Is(err, ErrPruned) {
+		t.Errorf("full reindex of a pruned chain: %v", err)
+	}
+	if _, err := NewBlockchain(store, params); err != nil {
+		t.Errorf("UTXO set after a failed reindex: %v", err)
+	}
+	store.Close()
+
+	// the pruned chain reopens and keeps growing
+	store, err = openFlatFileStore(dbFile, blocks, 1000, pruneConfig{target: 3000, depth: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	bc, err = NewBlockchain(store, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(address, "", 31, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPruneCrashRecovery(t *testing.T) {
+	params := privateParams()
+	dir := t.TempDir()
+	dbFile := filepath.Join(dir, "block-chain.db")
+	blocks := filepath.Join(dir, "blocks")
+	prune := pruneConfig{target: 3000, depth: 5}
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.Address(params))
+
+	// mine adds the blocks from one height to another, then closes the
+	// store without flushing the UTXO cache as a crash would leave it
+	mine := func(bc *Blockchain, from, to int) *UTXOStats {
+		t.Helper()
+		for h := from; h <= to; h++ {
+			cb, err := NewCoinbaseTX(address, "", h, 0, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		stats, err := UTxOSet{bc}.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bc.store.Close()
+		return stats
+	}
+	reopen := func(want *UTXOStats) *Blockchain {
+		t.Helper()
+		store, err := openFlatFileStore(dbFile, blocks, 1000, prune)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bc, err := ReindexBlockchain(store, params, ReindexOptions{})
+		if err != nil {
+			t.Fatalf("recovery: %v", err)
+		}
+		got, err := UTxOSet{bc}.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Hash, want.Hash) || got.Height != want.Height {
+			t.Errorf("recovered UTXO set = %+v, want %+v", got, want)
+		}
+		return bc
+	}
+	// prunedAbove counts the pruned block files holding blocks above height
+	prunedAbove := func(bc *Blockchain, height int) int {
+		n := 0
+		bc.store.View(func(tx StoreTx) error {
+			return tx.(kvStoreTx).tx.bucket(blockFilesBucket).ForEach(func(k, v []byte) error {
+				info, err := decodeBlockFileInfo(v)
+				if err == nil && info.Pruned && int(info.MaxHeight) > height {
+					n++
+				}
+				return err
+			})
+		})
+		return n
+	}
+
+	store, err := openFlatFileStore(dbFile, blocks, 1000, prune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := CreateBlockchain(store, address, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bc.utxo.maxLag != prune.depth {
+		t.Fatalf("cache lag limit %d, want the prune depth %d", bc.utxo.maxLag, prune.depth)
+	}
+
+	// a cache which is never flushed keeps every block it would replay
+	bc.utxo.maxLag = 0
+	want := mine(bc, 1, 30)
+	bc = reopen(want)
+	if n := prunedAbove(bc, 0); n != 0 {
+		t.Errorf("%d block files pruned above the stored UTXO set", n)
+	}
+
+	// the cache is flushed before it gets past the prune depth, so that
+	// pruning goes on while a crash only loses blocks which are kept
+	want = mine(bc, 31, 60)
+	bc = reopen(want)
+	defer bc.Close()
+	if n := prunedAbove(bc, 0); n == 0 {
+		t.Error("no block file pruned")
+	}
+}
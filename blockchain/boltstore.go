@@ -0,0 +1,418 @@
+package blockchain
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+const (
+	// blockIndexBucket maps block hashes to their location in the block files
+	blockIndexBucket = "blockIndexBucket"
+	// headersBucket holds the headers of the blocks in the block files
+	headersBucket = "headersBucket"
+	// blockFilesBucket maps the numbers of the block files to their info
+	blockFilesBucket = "blockFilesBucket"
+	// metaBucket holds the bookkeeping of the store
+	metaBucket = "metaBucket"
+)
+
+// pruneDepth the number of blocks below the tip which are never pruned,
+// their undo data is needed to disconnect them
+const pruneDepth = 288
+
+// blockFilesKey the end of the block files known to the index
+var blockFilesKey = []byte("blockfiles")
+
+// boltStore a Store kept in a bolt database file
+type boltStore struct {
+	db *bolt.DB
+	// files holds the block bodies, when nil they are kept in the database
+	files *blockFiles
+	prune pruneConfig
+	// writeMu serializes the updates appending to the block files, bolt
+	// releases its writer lock before a failed update rolls them back
+	writeMu sync.Mutex
+}
+
+// pruneDepth returns the prune depth, 0 when the store keeps every block
+func (s *boltStore) pruneDepth() int {
+	if s.prune.target == 0 {
+		return 0
+	}
+
+	return s.prune.depth
+}
+
+// pruneConfig when the bodies of old blocks are removed
+type pruneConfig struct {
+	// target the size of the block files to stay under, 0 keeps every block
+	target uint64
+	// depth the number of blocks below the tip to keep
+	depth int
+}
+
+// OpenBoltStore opens or creates a store in the bolt database file,
+// blocks are kept in the database along with everything else
+func OpenBoltStore(file string) (Store, error) {
+	db, err := openBoltDB(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+// OpenFlatFileStore opens or creates a store which appends raw blocks to
+// blk*.dat files in blocksDir, the bolt database only indexes them.
+// Blocks written to the database by OpenBoltStore stay readable.
+//
+// With a non-zero pruneTarget, in bytes, the oldest block files are
+// removed once the files take more space. The headers of their blocks
+// are kept, the last 288 blocks are never pruned.
+func OpenFlatFileStore(file, blocksDir string, pruneTarget uint64) (Store, error) {
+	maxFileSize := uint32(maxBlockFileSize)
+	if pruneTarget != 0 && pruneTarget/8 < maxBlockFileSize {
+		// small files, so that pruning one frees a fraction of the target
+		maxFileSize = uint32(pruneTarget / 8)
+	}
+
+	return openFlatFileStore(file, blocksDir, maxFileSize, pruneConfig{pruneTarget, pruneDepth})
+}
+
+func openFlatFileStore(file, blocksDir string, maxFileSize uint32, prune pruneConfig) (Store, error) {
+	db, err := openBoltDB(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos blockFilesPos
+	var pruned []uint32
+	err = db.View(func(tx *bolt.Tx) error {
+		pos = decodeBlockFilesPos(tx.Bucket([]byte(metaBucket)).Get(blockFilesKey))
+
+		return tx.Bucket([]byte(blockFilesBucket)).ForEach(func(k, v []byte) error {
+			info, err := decodeBlockFileInfo(v)
+			if err != nil {
+				return err
+			}
+			if info.Pruned {
+				pruned = append(pruned, binary.BigEndian.Uint32(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	files, err := openBlockFiles(blocksDir, maxFileSize, pos)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// pruned files left behind by a crash before their removal
+	for _, n := range pruned {
+		files.prune(n)
+	}
+	err = files.removePruned(true)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &boltStore{db: db, files: files, prune: prune}, nil
+}
+
+// openBoltDB opens the database file. A daemon may hold its lock,
+// so it gives up after a second instead of waiting forever.
+func openBoltDB(file string) (*bolt.DB, error) {
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range []string{blocksBucket, utxoBucket, txIndexBucket, undoBucket,
+			blockIndexBucket, headersBucket, blockFilesBucket, metaBucket} {
+			_, err := tx.CreateBucketIfNotExists([]byte(name))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (s *boltStore) View(fn func(tx StoreTx) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(kvStoreTx{boltTx{tx, s}})
+	})
+}
+
+func (s *boltStore) Update(fn func(tx StoreTx) error) error {
+	if s.files == nil {
+		return s.db.Update(func(tx *bolt.Tx) error {
+			return fn(kvStoreTx{boltTx{tx, s}})
+		})
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	pos := s.files.position()
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return fn(kvStoreTx{boltTx{tx, s}})
+	})
+	if err != nil {
+		s.files.removePruned(false)
+		// drop the blocks appended by the transaction
+		if terr := s.files.truncate(pos); terr != nil {
+			return fmt.Errorf("%v, rolling back block files: %v", err, terr)
+		}
+		return err
+	}
+
+	return s.files.removePruned(true)
+}
+
+func (s *boltStore) Close() error {
+	if s.files != nil {
+		s.files.close()
+	}
+
+	return s.db.Close()
+}
+
+type boltTx struct {
+	tx    *bolt.Tx
+	store *boltStore
+}
+
+func (t boltTx) bucket(name string) bucket {
+	return t.tx.Bucket([]byte(name))
+}
+
+func (t boltTx) resetBucket(name string) (bucket, error) {
+	err := t.tx.DeleteBucket([]byte(name))
+	if err != nil && err != bolt.ErrBucketNotFound {
+		return nil, err
+	}
+
+	b, err := t.tx.CreateBucket([]byte(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (t boltTx) blockStore() BlockStore {
+	if t.store.files == nil {
+		return kvBlocks{t}
+	}
+
+	return fileBlocks{t, t.store.files, t.store.prune}
+}
+
+// fileBlocks a BlockStore keeping block bodies in the block files
+// and their locations in the index bucket
+type fileBlocks struct {
+	tx    boltTx
+	files *blockFiles
+	prune pruneConfig
+}
+
+func (s fileBlocks) Block(hash []byte) (*Block, error) {
+	entry := s.tx.bucket(blockIndexBucket).Get(hash)
+	if entry == nil {
+		// written before the store used block files
+		return kvBlocks{s.tx}.Block(hash)
+	}
+
+	loc, err := decodeBlockLocation(entry)
+	if err != nil {
+		return nil, err
+	}
+	if loc.Status == blockPruned {
+		return nil, fmt.Errorf("block %x at height %d: %w", hash, loc.Height, ErrPruned)
+	}
+	d, err := s.files.read(loc)
+	if err != nil {
+		return nil, fmt.Errorf("block %x: %w", hash, err)
+	}
+
+	return DeserializeBlock(d)
+}
+
+func (s fileBlocks) Header(hash []byte) (*Block, error) {
+	d := s.tx.bucket(headersBucket).Get(hash)
+	if d == nil {
+		return kvBlocks{s.tx}.Header(hash)
+	}
+
+	return DeserializeBlock(d)
+}
+
+func (s fileBlocks) PutBlock(block *Block) error {
+	loc, err := s.files.append(block.Serialize(), block.Height)
+	if err != nil {
+		return err
+	}
+
+	err = s.tx.bucket(blockIndexBucket).Put(block.Hash, loc.encode())
+	if err != nil {
+		return err
+	}
+	err = s.tx.bucket(headersBucket).Put(block.Hash, block.header().Serialize())
+	if err != nil {
+		return err
+	}
+
+	pos := s.files.position()
+	info := blockFileInfo{Size: loc.Offset + blockRecordHeaderLen + loc.Length, MaxHeight: loc.Height}
+	old, err := s.fileInfo(loc.File)
+	if err != nil {
+		return err
+	}
+	if old.MaxHeight > info.MaxHeight {
+		info.MaxHeight = old.MaxHeight
+	}
+	err = s.putFileInfo(loc.File, info)
+	if err != nil {
+		return err
+	}
+	err = s.tx.bucket(metaBucket).Put(blockFilesKey, pos.encode())
+	if err != nil {
+		return err
+	}
+
+	return s.pruneFiles(block.Height)
+}
+
+func (s fileBlocks) Undo(hash []byte) ([]SpentOutput, error) {
+	return kvBlocks{s.tx}.Undo(hash)
+}
+
+func (s fileBlocks) PutUndo(hash []byte, spent []SpentOutput) error {
+	return kvBlocks{s.tx}.PutUndo(hash, spent)
+}
+
+func fileKey(n uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, n)
+}
+
+func (s fileBlocks) fileInfo(n uint32) (blockFileInfo, error) {
+	d := s.tx.bucket(blockFilesBucket).Get(fileKey(n))
+	if d == nil {
+		return blockFileInfo{}, nil
+	}
+
+	return decodeBlockFileInfo(d)
+}
+
+func (s fileBlocks) putFileInfo(n uint32, info blockFileInfo) error {
+	return s.tx.bucket(blockFilesBucket).Put(fileKey(n), info.encode())
+}
+
+// pruneFiles removes the oldest block files while the files take more than
+// the prune target. A file is only pruned when all of its blocks are deeper
+// than the prune depth below height, the file being written is never pruned.
+// Neither are the blocks above the best block of the stored UTXO set, they
+// are applied again when a crash loses the UTXO cache.
+func (s fileBlocks) pruneFiles(height int) error {
+	if s.prune.target == 0 || height < s.prune.depth {
+		return nil
+	}
+	current := s.files.position().File
+	limit := height - s.prune.depth
+	if _, utxoHeight := (kvUTXO{s.tx}).BestBlock(); utxoHeight < limit {
+		limit = utxoHeight
+	}
+
+	var total uint64
+	files := make(map[uint32]blockFileInfo)
+	var order []uint32
+	err := s.tx.bucket(blockFilesBucket).ForEach(func(k, v []byte) error {
+		info, err := decodeBlockFileInfo(v)
+		if err != nil {
+			return err
+		}
+		if !info.Pruned {
+			n := binary.BigEndian.Uint32(k)
+			files[n] = info
+			order = append(order, n)
+			total += uint64(info.Size)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	pruned := make(map[uint32]bool)
+	for _, n := range order {
+		info := files[n]
+		if total <= s.prune.target || n == current || int(info.MaxHeight) > limit {
+			break
+		}
+
+		info.Pruned = true
+		err := s.putFileInfo(n, info)
+		if err != nil {
+			return err
+		}
+		total -= uint64(info.Size)
+		pruned[n] = true
+	}
+	if len(pruned) == 0 {
+		return nil
+	}
+
+	// mark the blocks of the pruned files, their undo data goes with them
+	index := s.tx.bucket(blockIndexBucket)
+	var hashes [][]byte
+	var locs []blockLocation
+	err = index.ForEach(func(k, v []byte) error {
+		loc, err := decodeBlockLocation(v)
+		if err != nil {
+			return err
+		}
+		if pruned[loc.File] && loc.Status != blockPruned {
+			hashes = append(hashes, copyBytes(k))
+			locs = append(locs, loc)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, hash := range hashes {
+		locs[i].Status = blockPruned
+		err := index.Put(hash, locs[i].encode())
+		if err != nil {
+			return err
+		}
+		err = s.tx.bucket(undoBucket).Delete(hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	for n := range pruned {
+		s.files.prune(n)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package blockchain
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// The estimated sizes of the serialized parts of a transaction, which
+// coin selection weighs the fees with: the type descriptors gob writes
+// and the ID, an input revealing a compressed key with its signature, and
+// an output.
+const (
+	txBaseSize   = 290
+	txInputSize  = 140
+	txOutputSize = 30
+)
+
+// bnbMaxTries the most branches branch and bound explores
+const bnbMaxTries = 100000
+
+// ErrNoChangelessSelection branch and bound found no outputs paying the
+// target without change
+var ErrNoChangelessSelection = errors.New("no selection avoids change")
+
+// Fees the fee rates coin selection weighs, in fee per 1000 bytes
+type Fees struct {
+	// Rate the rate the transaction pays
+	Rate int
+	// LongTermRate the rate the change is expected to be spent at
+	LongTermRate int
+}
+
+// fee returns the fee of size bytes at rate, rounded up
+func fee(size, rate int) int {
+	return (size*rate + 999) / 1000
+}
+
+// InputFee returns the fee of spending an output
+func (f Fees) InputFee() int {
+	return fee(txInputSize, f.Rate)
+}
+
+// OutputFee returns the fee of an output
+func (f Fees) OutputFee() int {
+	return fee(txOutputSize, f.Rate)
+}
+
+// ChangeCost returns what making change costs: the fee of its output
+// now and of spending it later
+func (f Fees) ChangeCost() int {
+	return f.OutputFee() + fee(txInputSize, f.LongTermRate)
+}
+
+// EffectiveValue returns the value of an output less the fee of spending it
+func (f Fees) EffectiveValue(out TxOutput) int {
+	return out.Value - f.InputFee()
+}
+
+// Waste returns what spending the selected outputs to pay target loses
+// compared to the least the payment could cost: the fees of the inputs
+// beyond their long-term fees, and the change cost, or the excess given up
+// to the fee when there is no change
+func Waste(selected []UnspentOutput, target int, fees Fees) int {
+	waste := 0
+	value := 0
+	for _, uo := range selected {
+		waste += fees.InputFee() - fee(txInputSize, fees.LongTermRate)
+		value += fees.EffectiveValue(uo.Output)
+	}
+
+	excess := value - target
+	if excess > fees.ChangeCost() {
+		return waste + fees.ChangeCost()
+	}
+
+	return waste + excess
+}
+
+// CoinSelector picks the outputs a transaction spends
+type CoinSelector interface {
+	// Select returns outputs whose effective values sum to at least
+	// target, or ErrInsufficientFunds
+	Select(unspent []UnspentOutput, target int, fees Fees) ([]UnspentOutput, error)
+}
+
+// DefaultCoinSelector avoids change, or else spends the fewest outputs
+var DefaultCoinSelector CoinSelector = BranchAndBound{Fallback: LargestFirst{}}
+
+// ParseCoinSelector returns the selector of a name: bnb, largest,
+// smallest or random
+func ParseCoinSelector(s string) (CoinSelector, error) {
+	switch s {
+	case "bnb":
+		return DefaultCoinSelector, nil
+	case "largest":
+		return LargestFirst{}, nil
+	case "smallest":
+		return SmallestFirst{}, nil
+	case "random":
+		return RandomSelection{}, nil
+	}
+
+	return nil, fmt.Errorf("unknown coin selection %q", s)
+}
+
+// BranchAndBound searches the outputs whose effective values pay target
+// with an excess below the change cost, so the transaction needs no
+// change, and returns the ones wasting the least
+type BranchAndBound struct {
+	// Fallback selects when no outputs avoid change, none fails with
+	// ErrNoChangelessSelection
+	Fallback CoinSelector
+}
+
+// Select implements CoinSelector
+func (s BranchAndBound) Select(unspent []UnspentOutput, target int, fees Fees) ([]UnspentOutput, error) {
+	pool := spendable(unspent, fees)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return fees.EffectiveValue(pool[i].Output) > fees.EffectiveValue(pool[j].Output)
+	})
+	available := 0
+	for _, uo := range pool {
+		available += fees.EffectiveValue(uo.Output)
+	}
+	if available < target {
+		return nil, fmt.Errorf("%w: left %d", ErrInsufficientFunds, available)
+	}
+
+	inputWaste := fees.InputFee() - fee(txInputSize, fees.LongTermRate)
+	var chosen, best []int
+	bestWaste := -1
+	tries := 0
+
+	// the outputs from i on are included or not, the larger first
+	var search func(i, value, waste, remaining int)
+	search = func(i, value, waste, remaining int) {
+		tries++
+		if tries > bnbMaxTries || value > target+fees.ChangeCost() {
+			return
+		}
+		// more inputs only add waste when fees are high
+		if bestWaste >= 0 && inputWaste > 0 && waste > bestWaste {
+			return
+		}
+		if value >= target {
+			if w := waste + value - target; bestWaste < 0 || w < bestWaste {
+				best = append(best[:0], chosen...)
+				bestWaste = w
+			}
+			return
+		}
+		if i == len(pool) || value+remaining < target {
+			return
+		}
+
+		ev := fees.EffectiveValue(pool[i].Output)
+		chosen = append(chosen, i)
+		search(i+1, value+ev, waste+inputWaste, remaining-ev)
+		chosen = chosen[:len(chosen)-1]
+
+		// leaving out the outputs of the same value after this one
+		// would only repeat the branches above
+		j := i + 1
+		remaining -= ev
+		for j < len(pool) && fees.EffectiveValue(pool[j].Output) == ev {
+			remaining -= ev
+			j++
+		}
+		search(j, value, waste, remaining)
+	}
+	search(0, 0, 0, available)
+
+	if bestWaste < 0 {
+		if s.Fallback != nil {
+			return s.Fallback.Select(unspent, target, fees)
+		}
+		return nil, ErrNoChangelessSelection
+	}
+
+	selected := make([]UnspentOutput, len(best))
+	for k, i := range best {
+		selected[k] = pool[i]
+	}
+
+	return selected, nil
+}
+
+// LargestFirst spends the largest outputs first, the fewest inputs
+type LargestFirst struct{}
+
+// Select implements CoinSelector
+func (LargestFirst) Select(unspent []UnspentOutput, target int, fees Fees) ([]UnspentOutput, error) {
+	pool := spendable(unspent, fees)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].Output.Value > pool[j].Output.Value
+	})
+
+	return accumulate(pool, target, fees)
+}
+
+// SmallestFirst spends the smallest outputs first, consolidating the
+// wallet at the cost of more inputs
+type SmallestFirst struct{}
+
+// Select implements CoinSelector
+func (SmallestFirst) Select(unspent []UnspentOutput, target int, fees Fees) ([]UnspentOutput, error) {
+	pool := spendable(unspent, fees)
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].Output.Value < pool[j].Output.Value
+	})
+
+	return accumulate(pool, target, fees)
+}
+
+// RandomSelection spends the outputs in a random order, so the change
+// left behind does not grow small by a pattern
+type RandomSelection struct {
+	// Rand the source of the order, nil uses the one of math/rand
+	Rand *rand.Rand
+}
+
+// Select implements CoinSelector
+func (s RandomSelection) Select(unspent []UnspentOutput, target int, fees Fees) ([]UnspentOutput, error) {
+	pool := spendable(unspent, fees)
+	shuffle := rand.Shuffle
+	if s.Rand != nil {
+		shuffle = s.Rand.Shuffle
+	}
+	shuffle(len(pool), func(i, j int) {
+		pool[i], pool[j] = pool[j], pool[i]
+	})
+
+	return accumulate(pool, target, fees)
+}
+
+// spendable returns a copy of the outputs worth more than the fee of
+// spending them
+func spendable(unspent []UnspentOutput, fees Fees) []UnspentOutput {
+	var pool []UnspentOutput
+	for _, uo := range unspent {
+		if fees.EffectiveValue(uo.Output) > 0 {
+			pool = append(pool, uo)
+		}
+	}
+
+	return pool
+}
+
+// accumulate selects the outputs in order until they pay target and the
+// cost of the change, or only target when there are no more
+func accumulate(pool []UnspentOutput, target int, fees Fees) ([]UnspentOutput, error) {
+	var selected []UnspentOutput
+	value := 0
+	for _, uo := range pool {
+		selected = append(selected, uo)
+		value += fees.EffectiveValue(uo.Output)
+		if value >= target+fees.ChangeCost() {
+			return selected, nil
+		}
+	}
+	if value < target {
+		return nil, fmt.Errorf("%w: left %d", ErrInsufficientFunds, value)
+	}
+
+	return selected, nil
+}
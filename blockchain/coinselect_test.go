@@ -0,0 +1,285 @@
+package blockchain
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func unspentOutputs(values ...int) []UnspentOutput {
+	var unspent []UnspentOutput
+	for i, v := range values {
+		unspent = append(unspent, UnspentOutput{OutPoint{[]byte{byte(i)}, 0}, TxOutput{Value: v}})
+	}
+
+	return unspent
+}
+
+func selectedValues(selected []UnspentOutput) []int {
+	var values []int
+	for _, uo := range selected {
+		values = append(values, uo.Output.Value)
+	}
+	sort.Ints(values)
+
+	return values
+}
+
+func TestCoinSelectionWaste(t *testing.T) {
+	// spending an output costs 2, its long-term fee is 1, so change costs 2
+	fees := Fees{Rate: 10, LongTermRate: 5}
+	if fees.InputFee() != 2 || fees.ChangeCost() != 2 {
+		t.Fatalf("input fee %d, change cost %d", fees.InputFee(), fees.ChangeCost())
+	}
+	unspent := unspentOutputs(100, 2000, 500, 1000, 200, 1)
+
+	for _, c := range []struct {
+		target int
+		bnb    []int
+	}{
+		{1196, []int{200, 1000}},
+		{1195, []int{200, 1000}},
+		{998, []int{1000}},
+		{3790, []int{100, 200, 500, 1000, 2000}},
+	} {
+		bnb, err := BranchAndBound{}.Select(unspent, c.target, fees)
+		if err != nil {
+			t.Fatalf("target %d: %v", c.target, err)
+		}
+		if got := selectedValues(bnb); !equalInts(got, c.bnb) {
+			t.Errorf("target %d: branch and bound selected %v, want %v", c.target, got, c.bnb)
+		}
+		bnbWaste := Waste(bnb, c.target, fees)
+
+		// the others make change, and waste more
+		for _, s := range []CoinSelector{LargestFirst{}, SmallestFirst{}, RandomSelection{rand.New(rand.NewSource(1))}} {
+			selected, err := s.Select(unspent, c.target, fees)
+			if err != nil {
+				t.Fatalf("target %d: %T: %v", c.target, s, err)
+			}
+			if w := Waste(selected, c.target, fees); w < bnbWaste {
+				t.Errorf("target %d: %T selected %v wasting %d, branch and bound %d", c.target, s, selectedValues(selected), w, bnbWaste)
+			}
+		}
+	}
+
+	largest, err := LargestFirst{}.Select(unspent, 1500, fees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallest, err := SmallestFirst{}.Select(unspent, 1500, fees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := selectedValues(largest); !equalInts(got, []int{2000}) {
+		t.Errorf("largest first selected %v", got)
+	}
+	// the output worth less than its fee is left out
+	if got := selectedValues(smallest); !equalInts(got, []int{100, 200, 500, 1000}) {
+		t.Errorf("smallest first selected %v", got)
+	}
+	if Waste(largest, 1500, fees) >= Waste(smallest, 1500, fees) {
+		t.Errorf("largest first wastes %d, smallest first %d", Waste(largest, 1500, fees), Waste(smallest, 1500, fees))
+	}
+
+	// no selection avoids change
+	if _, err := (BranchAndBound{}).Select(unspent, 1300, fees); !errors.Is(err, ErrNoChangelessSelection) {
+		t.Errorf("changeless selection of 1300: %v", err)
+	}
+	selected, err := DefaultCoinSelector.Select(unspent, 1300, fees)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := selectedValues(selected); !equalInts(got, []int{2000}) {
+		t.Errorf("fallback selected %v", got)
+	}
+
+	for _, s := range []CoinSelector{DefaultCoinSelector, LargestFirst{}, SmallestFirst{}, RandomSelection{}} {
+		if _, err := s.Select(unspent, 3791, fees); !errors.Is(err, ErrInsufficientFunds) {
+			t.Errorf("%T selected more than there is: %v", s, err)
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestParseCoinSelector(t *testing.T) {
+	for _, name := range []string{"bnb", "largest", "smallest", "random"} {
+		if _, err := ParseCoinSelector(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := ParseCoinSelector("first"); err == nil {
+		t.Error("parsed an unknown selection")
+	}
+}
+
+func TestTransactionFee(t *testing.T) {
+	params := privateParams()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := CreateBlockchain(NewMemoryStore(), string(from.Address(params)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	utxo := UTxOSet{bc}
+	subsidy := params.Emission.Subsidy(0)
+
+	// the transaction pays its fee out of the change
+	fees := Fees{Rate: 10, LongTermRate: 10}
+	cc := &CoinControl{Selector: LargestFirst{}, Fees: fees}
+	tx, err := NewSendTransaction(from, []Payment{{string(to.Address(params)), 10}}, cc, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid := fee(txBaseSize+txOutputSize, fees.Rate) + fees.InputFee() + fees.OutputFee()
+	if len(tx.Vout) != 2 || tx.Vout[1].Value != subsidy-10-paid {
+		t.Errorf("paid a fee of %d, want %d", subsidy-tx.OutputValue(), paid)
+	}
+
+	// the excess not worth change goes to the fee
+	amount := subsidy - fee(txBaseSize+txOutputSize, fees.Rate) - fees.InputFee() - fees.ChangeCost()
+	tx, err = NewSendTransaction(from, []Payment{{string(to.Address(params)), amount}}, cc, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tx.Vout) != 1 {
+		t.Errorf("made change of %d", tx.Vout[len(tx.Vout)-1].Value)
+	}
+	if _, err := NewSendTransaction(from, []Payment{{string(to.Address(params)), subsidy}}, cc, &utxo); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("paid the whole output and a fee: %v", err)
+	}
+
+	// the miner collects the fee, and no more
+	miner, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid, err = bc.TransactionFee(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paid != subsidy-amount {
+		t.Errorf("transaction fee %d, want %d", paid, subsidy-amount)
+	}
+	cb, err := NewCoinbaseTX(string(miner.Address(params)), "", 1, paid+1, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, tx}); err == nil {
+		t.Fatal("coinbase claimed more than the fees")
+	}
+	cb, err = NewCoinbaseTX(string(miner.Address(params)), "", 1, paid, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, tx}); err != nil {
+		t.Fatal(err)
+	}
+	outs, err := utxo.FindUTXO(wallet.HashPublicKey(miner.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outs) != 1 || outs[0].Value != params.Emission.Subsidy(1)+paid {
+		t.Errorf("miner outputs %+v, want subsidy and fee %d", outs, paid)
+	}
+
+	// the fees move coins, they do not create any
+	issued, err := bc.IssuedSupply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	total, err := utxo.TotalAmount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued != total || issued != subsidy+params.Emission.Subsidy(1) {
+		t.Errorf("issued %d, UTXO set total %d", issued, total)
+	}
+}
+
+func TestSupplyCapWithFees(t *testing.T) {
+	params := *privateParams()
+	params.Emission = chaincfg.EmissionSchedule{InitialSubsidy: 50, MaxSupply: 120}
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := string(w.Address(&params))
+	bc, err := CreateBlockchain(NewMemoryStore(), address, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	utxo := UTxOSet{bc}
+
+	// every block pays a fee, also once the cap leaves no subsidy
+	cc := &CoinControl{Fees: Fees{Rate: 10}}
+	for height := 1; height <= 5; height++ {
+		tx, err := NewSendTransaction(w, []Payment{{address, 10}}, cc, &utxo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fee, err := bc.TransactionFee(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fee == 0 {
+			t.Fatal("the transaction pays no fee")
+		}
+
+		cb, err := NewCoinbaseTX(address, "", height, fee+1, &params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb, tx}); !errors.Is(err, ErrInvalidCoinbase) {
+			t.Fatalf("height %d: coinbase claimed more than the subsidy and fees: %v", height, err)
+		}
+		cb, err = NewCoinbaseTX(address, "", height, fee, &params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb, tx}); err != nil {
+			t.Fatal(err)
+		}
+
+		issued, err := bc.IssuedSupply()
+		if err != nil {
+			t.Fatal(err)
+		}
+		total, err := utxo.TotalAmount()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if issued != params.Emission.Supply(height) || issued != total || issued > params.Emission.MaxSupply {
+			t.Errorf("height %d: issued %d, UTXO set total %d, schedule %d", height, issued, total, params.Emission.Supply(height))
+		}
+	}
+	if got := params.Emission.Subsidy(5); got != 0 {
+		t.Fatalf("subsidy %d past the cap", got)
+	}
+}
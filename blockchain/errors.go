@@ -0,0 +1,35 @@
+package blockchain
+
+import "errors"
+
+var (
+	// ErrNotFound a block or transaction does not exist in the chain
+	ErrNotFound = errors.New("not found")
+	// ErrInvalidSignature an input is not signed by the owner of the output it spends
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrTxIDMismatch the ID of a transaction is not the hash of its data
+	ErrTxIDMismatch = errors.New("transaction ID does not match its data")
+	// ErrInvalidValue an output value is negative, or the outputs of a
+	// transaction exceed the outputs its inputs spend
+	ErrInvalidValue = errors.New("invalid output value")
+	// ErrInvalidHeight the height of a block is not one above its parent's
+	ErrInvalidHeight = errors.New("invalid block height")
+	// ErrInvalidCoinbase a block does not start with its only coinbase, or
+	// the coinbase pays more than the subsidy and the fees
+	ErrInvalidCoinbase = errors.New("invalid coinbase")
+	// ErrInsufficientFunds the spendable outputs of a sender don't cover the amount
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrGenesisMismatch a chain does not start from the genesis block of
+	// its network
+	ErrGenesisMismatch = errors.New("genesis block of another network")
+	// ErrNoBlockchain the database holds no chain yet
+	ErrNoBlockchain = errors.New("no existing blockchain found")
+	// ErrUTXOMismatch the UTXO set is not up to date with the tip of the chain
+	ErrUTXOMismatch = errors.New("UTXO set does not match the chain tip")
+	// ErrMissingInput an input spends an output that is spent or does not exist
+	ErrMissingInput = errors.New("input spends a missing or spent output")
+	// ErrPruned the transactions of a block have been pruned
+	ErrPruned = errors.New("block data pruned")
+	// ErrInvalidSnapshot a UTXO snapshot is malformed or does not match the pinned one
+	ErrInvalidSnapshot = errors.New("invalid UTXO snapshot")
+)
@@ -0,0 +1,156 @@
+package blockchain
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var errReadOnly = errors.New("store transaction is read-only")
+
+// memStore a Store kept in memory, for tests and simulations
+type memStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// NewMemoryStore creates an empty store which never touches the disk
+func NewMemoryStore() Store {
+	return &memStore{buckets: make(map[string]map[string][]byte)}
+}
+
+func (s *memStore) View(fn func(tx StoreTx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(kvStoreTx{&memTx{s, false, make(map[string]*memBucket)}})
+}
+
+func (s *memStore) Update(fn func(tx StoreTx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx := &memTx{s, true, make(map[string]*memBucket)}
+	err := fn(kvStoreTx{tx})
+	if err != nil {
+		return err
+	}
+
+	for name, b := range tx.buckets {
+		s.buckets[name] = b.commit()
+	}
+
+	return nil
+}
+
+func (s *memStore) Close() error {
+	return nil
+}
+
+// memTx collects the writes of a transaction until it commits
+type memTx struct {
+	store    *memStore
+	writable bool
+	buckets  map[string]*memBucket
+}
+
+func (t *memTx) bucket(name string) bucket {
+	b, ok := t.buckets[name]
+	if !ok {
+		b = &memBucket{base: t.store.buckets[name], writable: t.writable}
+		t.buckets[name] = b
+	}
+
+	return b
+}
+
+func (t *memTx) resetBucket(name string) (bucket, error) {
+	if !t.writable {
+		return nil, errReadOnly
+	}
+
+	b := &memBucket{writable: true}
+	t.buckets[name] = b
+
+	return b, nil
+}
+
+func (t *memTx) blockStore() BlockStore {
+	return kvBlocks{t}
+}
+
+// memBucket the pending writes of a transaction on top of a committed bucket
+type memBucket struct {
+	base     map[string][]byte
+	writes   map[string][]byte // a nil value deletes the key
+	writable bool
+}
+
+func (b *memBucket) Get(key []byte) []byte {
+	if v, ok := b.writes[string(key)]; ok {
+		return v
+	}
+
+	return b.base[string(key)]
+}
+
+func (b *memBucket) Put(key, value []byte) error {
+	return b.write(key, append([]byte{}, value...))
+}
+
+func (b *memBucket) Delete(key []byte) error {
+	return b.write(key, nil)
+}
+
+func (b *memBucket) write(key, value []byte) error {
+	if !b.writable {
+		return errReadOnly
+	}
+	if b.writes == nil {
+		b.writes = make(map[string][]byte)
+	}
+	b.writes[string(key)] = value
+
+	return nil
+}
+
+// ForEach visits the keys in byte order, like a bolt bucket
+func (b *memBucket) ForEach(fn func(k, v []byte) error) error {
+	var keys []string
+	for k := range b.base {
+		if _, ok := b.writes[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range b.writes {
+		if v != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		err := fn([]byte(k), b.Get([]byte(k)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commit applies the writes to the committed bucket and returns it
+func (b *memBucket) commit() map[string][]byte {
+	if b.base == nil {
+		b.base = make(map[string][]byte)
+	}
+	for k, v := range b.writes {
+		if v == nil {
+			delete(b.base, k)
+		} else {
+			b.base[k] = v
+		}
+	}
+
+	return b.base
+}
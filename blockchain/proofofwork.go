@@ -1,27 +1,24 @@
-package main
+package blockchain
 
 import (
 	"bytes"
 	"crypto/sha256"
-	"fmt"
 	"math"
 	"math/big"
-	"time"
 )
 
-const targetBits = 24
-
 // ProofOfWork the proof of work
 type ProofOfWork struct {
-	block  *Block
-	target *big.Int
+	block      *Block
+	target     *big.Int
+	targetBits int
 }
 
 // NewProofOfWork create a proof of work
-func NewProofOfWork(b *Block) *ProofOfWork {
+func NewProofOfWork(b *Block, targetBits int) *ProofOfWork {
 	target := big.NewInt(1)
 	target.Lsh(target, uint(256-targetBits))
-	return &ProofOfWork{b, target}
+	return &ProofOfWork{b, target, targetBits}
 }
 
 func (pow *ProofOfWork) prepartData(nonce int) []byte {
@@ -29,8 +26,9 @@ func (pow *ProofOfWork) prepartData(nonce int) []byte {
 		[][]byte{
 			pow.block.PrevBlockHash,
 			pow.block.HashTransactions(),
+			pow.block.HashWitnesses(),
 			IntToHex(pow.block.Timestamp),
-			IntToHex(targetBits),
+			IntToHex(int64(pow.targetBits)),
 			IntToHex(int64(nonce)),
 		},
 		[]byte{},
@@ -44,11 +42,9 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 	var hash [32]byte
 	nonce := 0
 
-	start := time.Now()
 	for nonce < math.MaxInt64 {
 		data := pow.prepartData(nonce)
 		hash = sha256.Sum256(data)
-		fmt.Printf("\r%x", hash)
 		hashInt.SetBytes(hash[:])
 		if hashInt.Cmp(pow.target) == -1 {
 			break
@@ -56,8 +52,6 @@ func (pow *ProofOfWork) Run() (int, []byte) {
 			nonce++
 		}
 	}
-	fmt.Println(" Mining costs: ", time.Now().Sub(start))
-	fmt.Println()
 
 	return nonce, hash[:]
 }
@@ -68,6 +62,6 @@ func (pow *ProofOfWork) Validate() bool {
 	data := pow.prepartData(pow.block.Nonce)
 	hash := sha256.Sum256(data)
 	hashInt.SetBytes(hash[:])
-	isValid := hashInt.Cmp(pow.target) == -1
+	isValid := hashInt.Cmp(pow.target) == -1 && bytes.Equal(hash[:], pow.block.Hash)
 	return isValid
 }
@@ -0,0 +1,281 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+var (
+	// ErrInvalidPSBT a partially signed transaction is malformed, or
+	// PSBTs of different transactions are combined
+	ErrInvalidPSBT = errors.New("invalid partially signed transaction")
+	// ErrPSBTIncomplete an input of a partially signed transaction lacks
+	// signatures
+	ErrPSBTIncomplete = errors.New("partially signed transaction is incomplete")
+)
+
+// PSBT a partially signed transaction: the unsigned transaction, the
+// outputs its inputs spend and the signatures collected for them. It
+// holds all a signer needs, so keys can sign it on a machine without the
+// chain, and several signers can each add theirs.
+type PSBT struct {
+	Tx     Transaction
+	Inputs []PSBTInput
+}
+
+// PSBTInput what is known of the input at the same index
+type PSBTInput struct {
+	PrevOut  TxOutput
+	HashType SigHashType
+	// RedeemScript the script of a pay-to-script-hash output, once a
+	// signer knowing it has signed
+	RedeemScript []byte
+	// Signatures the signatures without the hash type, by the hex of the
+	// public key signing
+	Signatures map[string][]byte
+}
+
+// NewPSBT starts a partially signed transaction of an unsigned one whose
+// inputs spend outputs of the UTXO set, every input is to be signed with
+// the hash type
+func (bc *Blockchain) NewPSBT(tx *Transaction, hashType SigHashType) (*PSBT, error) {
+	if tx.IsCoinbase() {
+		return nil, fmt.Errorf("%w: coinbase transaction", ErrInvalidPSBT)
+	}
+	prevOuts, err := bc.findPrevOuts(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PSBT{Tx: tx.TrimmedCopy(), Inputs: make([]PSBTInput, len(tx.Vin))}
+	p.Tx.ID = p.Tx.Hash()
+	for i, in := range tx.Vin {
+		p.Inputs[i] = PSBTInput{prevOuts[in.OutPoint().String()], hashType, nil, make(map[string][]byte)}
+	}
+
+	return p, nil
+}
+
+// DecodePSBT decodes a partially signed transaction Encode encoded
+func DecodePSBT(s string) (*PSBT, error) {
+	d, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPSBT, err)
+	}
+
+	var p PSBT
+	err = gob.NewDecoder(bytes.NewReader(d)).Decode(&p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPSBT, err)
+	}
+	if len(p.Inputs) != len(p.Tx.Vin) || p.Tx.IsCoinbase() {
+		return nil, fmt.Errorf("%w: %d inputs described for %d", ErrInvalidPSBT, len(p.Inputs), len(p.Tx.Vin))
+	}
+	for i := range p.Inputs {
+		if p.Inputs[i].Signatures == nil {
+			p.Inputs[i].Signatures = make(map[string][]byte)
+		}
+	}
+
+	return &p, nil
+}
+
+// Encode encodes the partially signed transaction in base64, to be passed
+// between the signers
+func (p *PSBT) Encode() string {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(p)
+	if err != nil {
+		// a PSBT holds no types gob cannot encode
+		panic(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// Sign adds the signatures of the keys of the wallets to the inputs they
+// may sign, and the redeem scripts they know. It returns the number of
+// signatures added.
+func (p *PSBT) Sign(ws *wallet.Wallets) (int, error) {
+	signed := 0
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		hash, err := p.Tx.sigHash(i, in.PrevOut, in.HashType)
+		if err != nil {
+			return signed, err
+		}
+
+		var signers []*wallet.Wallet
+		if in.PrevOut.KeyType == wallet.KeyScriptHash {
+			script, err := p.redeemScript(in, ws)
+			if err != nil {
+				continue
+			}
+			for _, k := range script.Keys {
+				w, err := ws.FindWallet(k.KeyType, wallet.HashPublicKey(k.PubKey))
+				if err == nil {
+					signers = append(signers, w)
+				}
+			}
+		} else {
+			w, err := ws.FindWallet(in.PrevOut.KeyType, in.PrevOut.PubKeyHash)
+			if err == nil {
+				signers = append(signers, w)
+			}
+		}
+
+		for _, w := range signers {
+			key := hex.EncodeToString(w.PublicKey)
+			if _, ok := in.Signatures[key]; ok {
+				continue
+			}
+			sig, err := w.Sign(hash)
+			if err != nil {
+				return signed, err
+			}
+			in.Signatures[key] = sig
+			signed++
+		}
+	}
+
+	return signed, nil
+}
+
+// redeemScript returns the script of a pay-to-script-hash input, the one
+// the PSBT holds or else the one of the wallets, which is then recorded
+func (p *PSBT) redeemScript(in *PSBTInput, ws *wallet.Wallets) (*wallet.RedeemScript, error) {
+	if in.RedeemScript != nil {
+		return wallet.ParseRedeemScript(in.RedeemScript)
+	}
+
+	script, err := ws.FindScript(in.PrevOut.PubKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	in.RedeemScript = script.Serialize()
+
+	return script, nil
+}
+
+// Combine adds the redeem scripts and signatures of PSBTs of the same
+// transaction
+func (p *PSBT) Combine(others ...*PSBT) error {
+	for _, other := range others {
+		if !bytes.Equal(other.Tx.Hash(), p.Tx.Hash()) || len(other.Inputs) != len(p.Inputs) {
+			return fmt.Errorf("%w: transaction %x combined with %x", ErrInvalidPSBT, other.Tx.Hash(), p.Tx.Hash())
+		}
+		for i, in := range other.Inputs {
+			if in.HashType != p.Inputs[i].HashType {
+				return fmt.Errorf("%w: input %d signed with %s and %s", ErrInvalidPSBT, i, in.HashType, p.Inputs[i].HashType)
+			}
+			if p.Inputs[i].RedeemScript == nil {
+				p.Inputs[i].RedeemScript = in.RedeemScript
+			}
+			for key, sig := range in.Signatures {
+				p.Inputs[i].Signatures[key] = sig
+			}
+		}
+	}
+
+	return nil
+}
+
+// Finalize fills the inputs of the transaction with the keys or redeem
+// scripts and the signatures collected, and verifies them. It returns
+// ErrPSBTIncomplete when an input lacks signatures.
+func (p *PSBT) Finalize() error {
+	tx := p.Tx.TrimmedCopy()
+	prevOuts := make(map[string]TxOutput)
+	for i, in := range p.Inputs {
+		op := tx.Vin[i].OutPoint()
+		prevOuts[op.String()] = in.PrevOut
+
+		err := p.finalizeInput(&tx, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tx.Verify(prevOuts)
+	if err != nil {
+		return err
+	}
+	p.Tx = tx
+
+	return nil
+}
+
+// finalizeInput fills the input at index i of tx with the signatures
+// collected for it
+func (p *PSBT) finalizeInput(tx *Transaction, i int) error {
+	if p.Inputs[i].PrevOut.KeyType == wallet.KeyScriptHash {
+		return p.finalizeScriptInput(tx, i)
+	}
+
+	return p.finalizeKeyInput(tx, i)
+}
+
+func (p *PSBT) finalizeKeyInput(tx *Transaction, i int) error {
+	in := p.Inputs[i]
+	for key, sig := range in.Signatures {
+		pubKey, err := hex.DecodeString(key)
+		if err != nil {
+			return fmt.Errorf("%w: input %d: %v", ErrInvalidPSBT, i, err)
+		}
+		if bytes.Equal(wallet.HashPublicKey(pubKey), in.PrevOut.PubKeyHash) {
+			tx.Vin[i].PubKey = pubKey
+			tx.Vin[i].Signature = append(append([]byte{}, sig...), byte(in.HashType))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: input %d is not signed", ErrPSBTIncomplete, i)
+}
+
+func (p *PSBT) finalizeScriptInput(tx *Transaction, i int) error {
+	in := p.Inputs[i]
+	if in.RedeemScript == nil {
+		return fmt.Errorf("%w: the redeem script of input %d is unknown", ErrPSBTIncomplete, i)
+	}
+	script, err := wallet.ParseRedeemScript(in.RedeemScript)
+	if err != nil {
+		return err
+	}
+
+	// the signatures are taken in the order of the keys
+	var sigs []byte
+	signed := 0
+	for index, k := range script.Keys {
+		sig, ok := in.Signatures[hex.EncodeToString(k.PubKey)]
+		if !ok {
+			continue
+		}
+		sigs = append(sigs, byte(index))
+		sigs = append(sigs, sig...)
+		signed++
+		if signed == script.Required {
+			tx.Vin[i].PubKey = in.RedeemScript
+			tx.Vin[i].Signature = append(sigs, byte(in.HashType))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: input %d has %d of %d signatures", ErrPSBTIncomplete, i, signed, script.Required)
+}
+
+// Complete reports whether every input of the transaction is signed
+func (p *PSBT) Complete() bool {
+	for _, in := range p.Tx.Vin {
+		if len(in.Signature) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
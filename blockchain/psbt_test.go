@@ -0,0 +1,119 @@
+package blockchain
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func TestPSBT(t *testing.T) {
+	params := privateParams()
+
+	// two signers, each with a wallet file of its own, share a 2-of-3 script
+	var signers []*wallet.Wallets
+	var keys []wallet.ScriptKey
+	for i := 0; i < 2; i++ {
+		ws, err := wallet.NewWallets(filepath.Join(t.TempDir(), "wallet.dat"), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j <= i; j++ {
+			address, err := ws.CreateWallet(wallet.KeySecp256k1, wallet.AddressBase58)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := ws.Wallet(address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys = append(keys, wallet.ScriptKey{KeyType: w.KeyType, PubKey: w.PublicKey})
+		}
+		signers = append(signers, ws)
+	}
+	script, err := wallet.NewMultiSigScript(2, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := script.Address(wallet.AddressBech32, params)
+	for _, ws := range signers {
+		ws.Scripts[address] = script
+	}
+
+	bc, err := CreateBlockchain(NewMemoryStore(), address, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+
+	tx, err := NewUnsignedTransaction(wallet.KeyScriptHash, script.Hash(), []Payment{{address, 20}}, nil, &UTxOSet{bc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := bc.NewPSBT(tx, SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := p.Encode()
+
+	// the first signer holds a key of the script, the second two
+	var partial []*PSBT
+	for i, ws := range signers {
+		p, err := DecodePSBT(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := p.Sign(ws)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != i+1 {
+			t.Errorf("signer %d added %d signatures", i, n)
+		}
+		partial = append(partial, p)
+	}
+	if err := partial[0].Finalize(); !errors.Is(err, ErrPSBTIncomplete) {
+		t.Fatalf("finalized one signature of two: %v", err)
+	}
+	if partial[0].Complete() {
+		t.Error("a failed finalization completed the transaction")
+	}
+
+	if err := partial[0].Combine(partial[1]); err != nil {
+		t.Fatal(err)
+	}
+	combined, err := DecodePSBT(partial[0].Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := combined.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if !combined.Complete() || string(combined.Tx.ID) != string(tx.ID) {
+		t.Fatalf("finalized transaction %x, want %x", combined.Tx.ID, tx.ID)
+	}
+	cb, err := NewCoinbaseTX(address, "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, &combined.Tx}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a PSBT of another transaction does not combine
+	other, err := NewUnsignedTransaction(wallet.KeyScriptHash, script.Hash(), []Payment{{address, 5}}, nil, &UTxOSet{bc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, err := bc.NewPSBT(other, SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := op.Combine(partial[1]); !errors.Is(err, ErrInvalidPSBT) {
+		t.Errorf("combined PSBTs of different transactions: %v", err)
+	}
+	if _, err := DecodePSBT("not base64"); !errors.Is(err, ErrInvalidPSBT) {
+		t.Errorf("decoded garbage: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// ErrInvalidRawTransaction a raw transaction or an outpoint of one is
+// malformed
+var ErrInvalidRawTransaction = errors.New("invalid raw transaction")
+
+// ParseOutPoint parses an outpoint written as the hex transaction ID and
+// the output index, the way OutPoint.String writes it
+func ParseOutPoint(s string) (OutPoint, error) {
+	txid, vout, ok := strings.Cut(s, ":")
+	if !ok {
+		return OutPoint{}, fmt.Errorf("%w: outpoint %q lacks an output index", ErrInvalidRawTransaction, s)
+	}
+	id, err := hex.DecodeString(txid)
+	if err != nil || len(id) == 0 {
+		return OutPoint{}, fmt.Errorf("%w: outpoint %q: bad transaction ID", ErrInvalidRawTransaction, s)
+	}
+	index, err := strconv.Atoi(vout)
+	if err != nil || index < 0 {
+		return OutPoint{}, fmt.Errorf("%w: outpoint %q: bad output index", ErrInvalidRawTransaction, s)
+	}
+
+	return OutPoint{id, index}, nil
+}
+
+// NewRawTransaction create an unsigned transaction spending the outpoints
+// to the outputs, it is neither checked against the chain nor signed
+func NewRawTransaction(inputs []OutPoint, outputs []TxOutput) (*Transaction, error) {
+	if len(inputs) == 0 || len(outputs) == 0 {
+		return nil, fmt.Errorf("%w: %d inputs and %d outputs", ErrInvalidRawTransaction, len(inputs), len(outputs))
+	}
+
+	tx := Transaction{Vout: outputs}
+	for _, op := range inputs {
+		tx.Vin = append(tx.Vin, TxInput{op.Txid, op.Vout, nil, nil})
+	}
+	if tx.IsCoinbase() {
+		return nil, fmt.Errorf("%w: coinbase transaction", ErrInvalidRawTransaction)
+	}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// DecodeRawTransaction decodes a transaction Hex encoded
+func DecodeRawTransaction(s string) (*Transaction, error) {
+	d, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRawTransaction, err)
+	}
+
+	var tx Transaction
+	err = gob.NewDecoder(bytes.NewReader(d)).Decode(&tx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRawTransaction, err)
+	}
+
+	return &tx, nil
+}
+
+// Hex encodes the serialized transaction in hex
+func (t Transaction) Hex() string {
+	return hex.EncodeToString(t.Serialize())
+}
+
+// SignRawTransaction signs with the hash type the inputs of the
+// transaction the wallets hold the keys or redeem scripts of. The inputs
+// already signed, by an external signer for one, are kept. It reports
+// whether every input is signed.
+func (bc *Blockchain) SignRawTransaction(tx *Transaction, ws *wallet.Wallets, hashType SigHashType) (bool, error) {
+	p, err := bc.NewPSBT(tx, hashType)
+	if err != nil {
+		return false, err
+	}
+	_, err = p.Sign(ws)
+	if err != nil {
+		return false, err
+	}
+
+	complete := true
+	for i := range tx.Vin {
+		if len(tx.Vin[i].Signature) != 0 {
+			continue
+		}
+		err := p.finalizeInput(tx, i)
+		if errors.Is(err, ErrPSBTIncomplete) {
+			complete = false
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return complete, nil
+}
@@ -0,0 +1,182 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func TestParseOutPoint(t *testing.T) {
+	op, err := ParseOutPoint("00ff:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(op.Txid, []byte{0, 0xff}) || op.Vout != 2 || op.String() != "00ff:2" {
+		t.Errorf("parsed %s", op)
+	}
+
+	for _, s := range []string{"00ff", ":2", "0g:2", "00ff:", "00ff:-1", "00ff:x"} {
+		if _, err := ParseOutPoint(s); !errors.Is(err, ErrInvalidRawTransaction) {
+			t.Errorf("%q: %v", s, err)
+		}
+	}
+}
+
+func TestRawTransaction(t *testing.T) {
+	params := privateParams()
+
+	// the wallets hold the key of a, b signs on their own
+	ws, err := wallet.NewWallets(filepath.Join(t.TempDir(), "wallet.dat"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addressA, err := ws.CreateWallet(wallet.KeySecp256k1, wallet.AddressBech32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc, err := CreateBlockchain(NewMemoryStore(), string(b.Address(params)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	var coinbases []*Transaction
+	for h, address := range []string{addressA, string(b.Address(params))} {
+		cb, err := NewCoinbaseTX(address, "", h+1, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+			t.Fatal(err)
+		}
+		coinbases = append(coinbases, cb)
+	}
+
+	pay, err := NewTxOutput(2*params.Emission.Subsidy(0), string(b.Address(params)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := []OutPoint{{coinbases[0].ID, 0}, {coinbases[1].ID, 0}}
+	tx, err := NewRawTransaction(inputs, []TxOutput{*pay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err = DecodeRawTransaction(tx.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tx.ID, tx.Hash()) {
+		t.Fatalf("ID %x of hash %x", tx.ID, tx.Hash())
+	}
+	if _, err := DecodeRawTransaction(tx.Hex()[2:]); !errors.Is(err, ErrInvalidRawTransaction) {
+		t.Errorf("decoded a cut transaction: %v", err)
+	}
+
+	complete, err := bc.SignRawTransaction(tx, ws, SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if complete || len(tx.Vin[0].Signature) == 0 || len(tx.Vin[1].Signature) != 0 {
+		t.Fatalf("wallets of a signed: complete %v, %s", complete, tx)
+	}
+
+	// the external signer signs the input of b, which is kept
+	tx.Vin[1].PubKey = b.PublicKey
+	if err := tx.SignInput(1, b, coinbases[1].Vout[0], SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+	signedB := tx.Vin[1].Signature
+	tx, err = DecodeRawTransaction(tx.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	complete, err = bc.SignRawTransaction(tx, ws, SigHashAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !complete || !bytes.Equal(tx.Vin[1].Signature, signedB) {
+		t.Fatalf("complete %v, input of b %x", complete, tx.Vin[1].Signature)
+	}
+
+	cb, err := NewCoinbaseTX(addressA, "", 3, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, tx}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRawTransactionValues(t *testing.T) {
+	params := privateParams()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := CreateBlockchain(NewMemoryStore(), string(from.Address(params)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	unspent, err := (&UTxOSet{bc}).ListUnspent(wallet.HashPublicKey(from.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevOut := unspent[0]
+	subsidy := prevOut.Output.Value
+	output := func(value int) TxOutput {
+		return TxOutput{value, wallet.HashPublicKey(from.PublicKey), from.KeyType}
+	}
+	mine := func(outputs ...TxOutput) error {
+		tx, err := NewRawTransaction([]OutPoint{prevOut.OutPoint}, outputs)
+		if err != nil {
+			return err
+		}
+		tx.Vin[0].PubKey = from.PublicKey
+		if err := tx.SignInput(0, from, prevOut.Output, SigHashAll); err != nil {
+			return err
+		}
+		cb, err := NewCoinbaseTX(string(from.Address(params)), "", 1, 0, params)
+		if err != nil {
+			return err
+		}
+		_, err = bc.AddBlock([]*Transaction{cb, tx})
+		return err
+	}
+
+	for _, outputs := range [][]TxOutput{
+		{output(1000000000)},
+		{output(subsidy + 1)},
+		{output(subsidy + 10), output(-10)},
+		{output(math.MaxInt), output(math.MaxInt)},
+	} {
+		if err := mine(outputs...); !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("mined outputs %v: %v", outputs, err)
+		}
+	}
+
+	// a coinbase must not pay more by paying a negative output
+	cb, err := NewCoinbaseTX(string(from.Address(params)), "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb.Vout = append(cb.Vout, output(-10))
+	cb.Vout[0].Value += 10
+	cb.ID = cb.Hash()
+	if _, err := bc.AddBlock([]*Transaction{cb}); !errors.Is(err, ErrInvalidValue) {
+		t.Errorf("mined a coinbase with a negative output: %v", err)
+	}
+
+	if err := mine(output(subsidy - 1)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,94 @@
+package blockchain
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// DefaultSigCacheSize the number of verified signatures a chain remembers
+const DefaultSigCacheSize = 50000
+
+// sigCacheKey identifies a signature check: the key type, the hash of
+// the signed data, the public key and the signature
+type sigCacheKey [sha256.Size]byte
+
+func newSigCacheKey(keyType wallet.KeyType, hash, pubKey, sig []byte) sigCacheKey {
+	h := sha256.New()
+	h.Write([]byte{byte(keyType)})
+	h.Write(hash)
+	h.Write(pubKey)
+	h.Write(sig)
+
+	var key sigCacheKey
+	copy(key[:], h.Sum(nil))
+
+	return key
+}
+
+// sigCache remembers the signatures found valid, least recently used
+// first out. Invalid ones are not kept, a block failing verification is
+// rejected anyway. A nil cache remembers nothing.
+type sigCache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // of sigCacheKey, most recently used at the front
+	entries map[sigCacheKey]*list.Element
+}
+
+func newSigCache(size int) *sigCache {
+	return &sigCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[sigCacheKey]*list.Element),
+	}
+}
+
+// contains reports whether the signature was found valid before
+func (c *sigCache) contains(key sigCacheKey) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(e)
+	}
+
+	return ok
+}
+
+// add remembers a valid signature, evicting the least recently used one
+// when the cache is full
+func (c *sigCache) add(key sigCacheKey) {
+	if c == nil || c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	for c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		delete(c.entries, oldest.Value.(sigCacheKey))
+		c.order.Remove(oldest)
+	}
+	c.entries[key] = c.order.PushFront(key)
+}
+
+func (c *sigCache) len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
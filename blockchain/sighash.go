@@ -0,0 +1,91 @@
+package blockchain
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// SigHashType tells what a signature commits to. It is the last byte of
+// the signature data of an input, and the last byte the signature hash
+// covers.
+type SigHashType byte
+
+const (
+	// SigHashAll commits to every input and output
+	SigHashAll SigHashType = 0x01
+	// SigHashNone commits to the inputs only, anyone may change the outputs
+	SigHashNone SigHashType = 0x02
+	// SigHashSingle commits to the inputs and the output at the index of
+	// the input signed
+	SigHashSingle SigHashType = 0x03
+	// SigHashAnyoneCanPay combined with another type commits to the input
+	// signed only, others may add inputs, as the contributors of a
+	// crowdfunding transaction do
+	SigHashAnyoneCanPay SigHashType = 0x80
+)
+
+const sigHashBaseMask = 0x1f
+
+var sigHashNames = map[SigHashType]string{
+	SigHashAll:    "ALL",
+	SigHashNone:   "NONE",
+	SigHashSingle: "SINGLE",
+}
+
+func (t SigHashType) String() string {
+	name, ok := sigHashNames[t&sigHashBaseMask]
+	if !ok || t&^(sigHashBaseMask|SigHashAnyoneCanPay) != 0 {
+		return fmt.Sprintf("SigHashType(0x%02x)", byte(t))
+	}
+	if t&SigHashAnyoneCanPay != 0 {
+		name += "|ANYONECANPAY"
+	}
+
+	return name
+}
+
+// ParseSigHashType returns the hash type of a name String returns
+func ParseSigHashType(name string) (SigHashType, error) {
+	base, acp := strings.CutSuffix(strings.ToUpper(name), "|ANYONECANPAY")
+	for t, n := range sigHashNames {
+		if n == base {
+			if acp {
+				t |= SigHashAnyoneCanPay
+			}
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown signature hash type: %s", name)
+}
+
+// sigHash returns the hash input index signs: the transaction without
+// signatures and keys, the input holding the public key hash of the
+// output it spends, trimmed to what the hash type commits to, and the
+// hash type
+func (t *Transaction) sigHash(index int, prevOut TxOutput, hashType SigHashType) ([]byte, error) {
+	copyTX := t.TrimmedCopy()
+	copyTX.ID = []byte{}
+	copyTX.Vin[index].PubKey = prevOut.PubKeyHash
+
+	switch hashType &^ SigHashAnyoneCanPay {
+	case SigHashAll:
+	case SigHashNone:
+		copyTX.Vout = nil
+	case SigHashSingle:
+		if index >= len(copyTX.Vout) {
+			return nil, fmt.Errorf("%w: input %d signs a missing output with %s", ErrInvalidSignature, index, hashType)
+		}
+		copyTX.Vout = copyTX.Vout[index : index+1]
+	default:
+		return nil, fmt.Errorf("%w: unknown hash type 0x%02x", ErrInvalidSignature, byte(hashType))
+	}
+	if hashType&SigHashAnyoneCanPay != 0 {
+		copyTX.Vin = copyTX.Vin[index : index+1]
+	}
+
+	hash := sha256.Sum256(append(copyTX.Serialize(), byte(hashType)))
+
+	return hash[:], nil
+}
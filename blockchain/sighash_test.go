@@ -0,0 +1,134 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func TestSigHashTypeNames(t *testing.T) {
+	for _, hashType := range []SigHashType{SigHashAll, SigHashNone, SigHashSingle, SigHashAll | SigHashAnyoneCanPay, SigHashSingle | SigHashAnyoneCanPay} {
+		got, err := ParseSigHashType(hashType.String())
+		if err != nil || got != hashType {
+			t.Errorf("%s parsed to %s: %v", hashType, got, err)
+		}
+	}
+	if _, err := ParseSigHashType("SOME"); err == nil {
+		t.Error("parsed an unknown hash type")
+	}
+}
+
+func TestSigHashTypes(t *testing.T) {
+	params := privateParams()
+
+	// two contributors with a coinbase output each, and a recipient
+	var contributors []*wallet.Wallet
+	for i := 0; i < 3; i++ {
+		w, err := wallet.NewWallet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contributors = append(contributors, w)
+	}
+	a, b, recipient := contributors[0], contributors[1], contributors[2]
+	bc, err := CreateBlockchain(NewMemoryStore(), string(recipient.Address(params)), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+	var coinbases []*Transaction
+	for h, w := range []*wallet.Wallet{a, b} {
+		cb, err := NewCoinbaseTX(string(w.Address(params)), "", h+1, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+			t.Fatal(err)
+		}
+		coinbases = append(coinbases, cb)
+	}
+	cbA, cbB := coinbases[0], coinbases[1]
+
+	subsidy := params.Emission.Subsidy(0)
+	inA := TxInput{cbA.ID, 0, nil, a.PublicKey}
+	inB := TxInput{cbB.ID, 0, nil, b.PublicKey}
+	pay := TxOutput{2 * subsidy, wallet.HashPublicKey(recipient.PublicKey), recipient.KeyType}
+	contribution := TxOutput{subsidy, wallet.HashPublicKey(recipient.PublicKey), recipient.KeyType}
+	change := TxOutput{subsidy, wallet.HashPublicKey(a.PublicKey), a.KeyType}
+
+	verify := func(tx *Transaction) error {
+		tx.ID = tx.Hash()
+		return bc.VerifyTransaction(tx)
+	}
+
+	// crowdfunding: each contributor adds and signs an input
+	tx := &Transaction{Vin: []TxInput{inA}, Vout: []TxOutput{pay}}
+	if err := tx.SignInput(0, a, cbA.Vout[0], SigHashAll|SigHashAnyoneCanPay); err != nil {
+		t.Fatal(err)
+	}
+	signedA := tx.Vin[0]
+	tx.Vin = append(tx.Vin, inB)
+	if err := tx.SignInput(1, b, cbB.Vout[0], SigHashAll|SigHashAnyoneCanPay); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(tx); err != nil {
+		t.Fatalf("crowdfunding transaction: %v", err)
+	}
+	tx.Vout[0].Value--
+	if err := verify(tx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("crowdfunding transaction paying less: %v", err)
+	}
+
+	// a signature of every input breaks when one is added
+	tx = &Transaction{Vin: []TxInput{inA}, Vout: []TxOutput{pay}}
+	if err := tx.SignInput(0, a, cbA.Vout[0], SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+	tx.Vin = append(tx.Vin, inB)
+	if err := tx.SignInput(1, b, cbB.Vout[0], SigHashAll); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(tx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("input added after an ALL signature: %v", err)
+	}
+
+	// NONE lets anyone change the outputs, SINGLE the other outputs only
+	tx = &Transaction{Vin: []TxInput{inA, inB}, Vout: []TxOutput{change, contribution}}
+	if err := tx.SignInput(0, a, cbA.Vout[0], SigHashSingle); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.SignInput(1, b, cbB.Vout[0], SigHashNone); err != nil {
+		t.Fatal(err)
+	}
+	tx.Vout[1].PubKeyHash = wallet.HashPublicKey(b.PublicKey)
+	if err := verify(tx); err != nil {
+		t.Errorf("other output changed: %v", err)
+	}
+	tx.Vout[0].Value--
+	if err := verify(tx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("output of a SINGLE signature changed: %v", err)
+	}
+	if err := tx.SignInput(1, b, cbB.Vout[0], SigHashSingle); err != nil {
+		t.Fatal(err)
+	}
+	tx.Vout = tx.Vout[:1]
+	if err := tx.SignInput(1, b, cbB.Vout[0], SigHashSingle); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("SINGLE signature of a missing output: %v", err)
+	}
+
+	// the hash type is signed too
+	tx = &Transaction{Vin: []TxInput{inA}, Vout: []TxOutput{contribution}}
+	if err := tx.SignInput(0, a, cbA.Vout[0], SigHashNone); err != nil {
+		t.Fatal(err)
+	}
+	tx.Vin[0].Signature[len(tx.Vin[0].Signature)-1] = byte(SigHashNone | SigHashAnyoneCanPay)
+	if err := verify(tx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("hash type changed: %v", err)
+	}
+	tx.Vin[0] = signedA
+	tx.Vin[0].Signature = append(append([]byte{}, signedA.Signature[:len(signedA.Signature)-1]...), 0x04)
+	if err := verify(tx); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("unknown hash type: %v", err)
+	}
+}
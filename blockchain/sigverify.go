@@ -0,0 +1,127 @@
+package blockchain
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// sigCheck the check of the signature of one transaction input
+type sigCheck struct {
+	tx      *Transaction
+	index   int
+	prevOut TxOutput
+}
+
+// verify checks the input is signed by the owner of the output it spends:
+// the key it pays to, or the keys of the redeem script whose hash it pays
+// to, over what the hash type ending the signature data commits to. The
+// signatures found valid are remembered by cache.
+func (c sigCheck) verify(cache *sigCache) error {
+	in := c.tx.Vin[c.index]
+	if !in.CanUnlockOutputWith(c.prevOut.PubKeyHash) {
+		return fmt.Errorf("%w: input %d of %x is not signed by the output owner", ErrInvalidSignature, c.index, c.tx.ID)
+	}
+
+	if len(in.Signature) == 0 {
+		return fmt.Errorf("%w: input %d of %x is not signed", ErrInvalidSignature, c.index, c.tx.ID)
+	}
+	sig := in.Signature[:len(in.Signature)-1]
+	hash, err := c.tx.sigHash(c.index, c.prevOut, SigHashType(in.Signature[len(in.Signature)-1]))
+	if err != nil {
+		return fmt.Errorf("input %d of %x: %w", c.index, c.tx.ID, err)
+	}
+	if c.prevOut.KeyType != wallet.KeyScriptHash {
+		return c.verifySignature(cache, c.prevOut.KeyType, in.PubKey, hash, sig)
+	}
+
+	script, err := wallet.ParseRedeemScript(in.PubKey)
+	if err != nil {
+		return fmt.Errorf("%w: input %d of %x: %v", ErrInvalidSignature, c.index, c.tx.ID, err)
+	}
+	sigs, err := script.Signatures(sig)
+	if err != nil {
+		return fmt.Errorf("%w: input %d of %x: %v", ErrInvalidSignature, c.index, c.tx.ID, err)
+	}
+	for _, sig := range sigs {
+		k := script.Keys[sig.Index]
+		err := c.verifySignature(cache, k.KeyType, k.PubKey, hash, sig.Signature)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c sigCheck) verifySignature(cache *sigCache, keyType wallet.KeyType, pubKey, hash, sig []byte) error {
+	key := newSigCacheKey(keyType, hash, pubKey, sig)
+	if cache.contains(key) {
+		return nil
+	}
+
+	err := wallet.VerifySignature(keyType, pubKey, hash, sig)
+	if err != nil {
+		return fmt.Errorf("%w: input %d of %x: %v", ErrInvalidSignature, c.index, c.tx.ID, err)
+	}
+	cache.add(key)
+
+	return nil
+}
+
+// verifySignatures runs the checks on a pool of workers, 0 workers uses
+// one per CPU. The remaining checks are skipped after a failure, the
+// error returned is the one of the first failing check in order.
+func verifySignatures(checks []sigCheck, cache *sigCache, workers int) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(checks) {
+		workers = len(checks)
+	}
+	if workers <= 1 {
+		for _, c := range checks {
+			err := c.verify(cache)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, len(checks))
+	var failed atomic.Bool
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			// the checks are taken in order, so every check before a
+			// failing one is run
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= len(checks) {
+					return
+				}
+				errs[i] = checks[i].verify(cache)
+				if errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
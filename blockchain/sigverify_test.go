@@ -0,0 +1,246 @@
+package blockchain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// spendCoinbases mines n blocks paying a wallet and returns a transaction
+// spending all of their coinbase outputs
+func spendCoinbases(tb testing.TB, n int) (*Blockchain, *Transaction) {
+	params := privateParams()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	fromAddr := string(from.Address(params))
+
+	bc, err := CreateBlockchain(NewMemoryStore(), fromAddr, params)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	amount := params.Emission.Subsidy(0)
+	for h := 1; h < n; h++ {
+		cb, err := NewCoinbaseTX(fromAddr, "", h, 0, params)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+			tb.Fatal(err)
+		}
+		amount += cb.OutputValue()
+	}
+
+	tx, err := NewUTXOTransaction(from, string(to.Address(params)), amount, &UTxOSet{bc})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(tx.Vin) != n {
+		tb.Fatalf("transaction spends %d outputs, want %d", len(tx.Vin), n)
+	}
+
+	return bc, tx
+}
+
+func TestVerifySignatures(t *testing.T) {
+	bc, tx := spendCoinbases(t, 16)
+	defer bc.Close()
+
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	if got := bc.sigs.len(); got != len(tx.Vin) {
+		t.Errorf("%d signatures cached, want %d", got, len(tx.Vin))
+	}
+
+	// the cached signatures let the block through without checking them
+	miner, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(string(miner.Address(bc.params)), "", 16, 0, bc.params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.checkTransactions([]*Transaction{cb, tx}, 16); err != nil {
+		t.Errorf("block with a verified transaction: %v", err)
+	}
+
+	// every failure is found, the first one in order is reported
+	checks, err := bc.sigChecks(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := *tx
+	bad.Vin = append([]TxInput{}, tx.Vin...)
+	for _, i := range []int{5, 12} {
+		sig := append([]byte{}, bad.Vin[i].Signature...)
+		sig[0] ^= 0xff
+		bad.Vin[i].Signature = sig
+	}
+	for i := range checks {
+		checks[i].tx = &bad
+	}
+	for _, workers := range []int{1, 4, len(checks)} {
+		err := verifySignatures(checks, nil, workers)
+		if !errors.Is(err, ErrInvalidSignature) || !strings.Contains(err.Error(), "input 5 ") {
+			t.Errorf("%d workers: %v, want an invalid signature of input 5", workers, err)
+		}
+	}
+	if err := bc.VerifyTransaction(&bad); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("verify a tampered transaction with the cache: %v", err)
+	}
+}
+
+func TestSigCache(t *testing.T) {
+	c := newSigCache(2)
+	a := newSigCacheKey(wallet.KeyP256, []byte("hash"), []byte("key"), []byte("a"))
+	b := newSigCacheKey(wallet.KeyP256, []byte("hash"), []byte("key"), []byte("b"))
+	d := newSigCacheKey(wallet.KeyP256, []byte("hash"), []byte("key"), []byte("d"))
+
+	c.add(a)
+	c.add(b)
+	if !c.contains(a) {
+		t.Fatal("added signature is not cached")
+	}
+	// b is now the least recently used
+	c.add(d)
+	if c.contains(b) || !c.contains(a) || !c.contains(d) || c.len() != 2 {
+		t.Errorf("cache after eviction: a %v, b %v, d %v, %d entries", c.contains(a), c.contains(b), c.contains(d), c.len())
+	}
+
+	var none *sigCache
+	none.add(a)
+	if none.contains(a) {
+		t.Error("nil cache remembered a signature")
+	}
+}
+
+func BenchmarkVerifySignatures(b *testing.B) {
+	bc, tx := spendCoinbases(b, 200)
+	defer bc.Close()
+
+	checks, err := bc.sigChecks(tx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := verifySignatures(checks, nil, 1); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := verifySignatures(checks, nil, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		cache := newSigCache(DefaultSigCacheSize)
+		if err := verifySignatures(checks, cache, 0); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if err := verifySignatures(checks, cache, 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestScriptHashSpend(t *testing.T) {
+	params := privateParams()
+
+	var signers []*wallet.Wallet
+	var keys []wallet.ScriptKey
+	for i := 0; i < 3; i++ {
+		w, err := wallet.NewWalletWithKeyType(wallet.KeySchnorr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, w)
+		keys = append(keys, wallet.ScriptKey{KeyType: w.KeyType, PubKey: w.PublicKey})
+	}
+	script, err := wallet.NewMultiSigScript(2, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bc, err := CreateBlockchain(NewMemoryStore(), script.Address(wallet.AddressBase58, params), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bc.Close()
+
+	// one wallet does not satisfy the script
+	one := wallet.ScriptSpender{Script: script, Wallets: signers[2:]}
+	if _, err := NewUTXOTransaction(one, string(to.Address(params)), 10, &UTxOSet{bc}); !errors.Is(err, wallet.ErrNotFound) {
+		t.Fatalf("spent with one signature: %v", err)
+	}
+
+	spender := wallet.ScriptSpender{Script: script, Wallets: signers[1:]}
+	tx, err := NewUTXOTransaction(spender, string(to.Address(params)), 10, &UTxOSet{bc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.VerifyTransaction(tx); err != nil {
+		t.Fatal(err)
+	}
+	// the change goes back to the script
+	if change := tx.Vout[1]; change.KeyType != wallet.KeyScriptHash || change.Value != params.Emission.Subsidy(0)-10 {
+		t.Errorf("change output %+v", change)
+	}
+
+	// another script, even of the same keys, does not match the hash
+	other, err := wallet.NewMultiSigScript(1, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := *tx
+	bad.Vin = append([]TxInput{}, tx.Vin...)
+	bad.Vin[0].PubKey = other.Serialize()
+	if err := bc.VerifyTransaction(&bad); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("input revealing another script: %v", err)
+	}
+
+	bad.Vin[0] = tx.Vin[0]
+	sigs := append([]byte{}, tx.Vin[0].Signature...)
+	sigs[len(sigs)-1] ^= 1
+	bad.Vin[0].Signature = sigs
+	if err := bc.VerifyTransaction(&bad); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("altered signature: %v", err)
+	}
+
+	cb, err := NewCoinbaseTX(string(to.Address(params)), "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, tx}); err != nil {
+		t.Fatal(err)
+	}
+	balance, err := (&UTxOSet{bc}).FindUTXO(script.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(balance) != 1 || balance[0].Value != params.Emission.Subsidy(0)-10 {
+		t.Errorf("script outputs %+v", balance)
+	}
+}
@@ -0,0 +1,360 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// snapshotMagic starts a UTXO snapshot file
+var snapshotMagic = []byte("utxo")
+
+const snapshotVersion = 1
+
+// DumpUTXOSnapshot writes the UTXO set to w along with the block it is up
+// to date with. The file starts with the magic, the version byte, the
+// length-prefixed base block and the number of outputs, then every
+// output follows in the order of its outpoint.
+func (bc *Blockchain) DumpUTXOSnapshot(w io.Writer) (*UTXOStats, error) {
+	var stats *UTXOStats
+	bw := bufio.NewWriter(w)
+
+	err := bc.view(func(tx StoreTx) error {
+		hash, height := tx.UTXO().BestBlock()
+		base, err := tx.Blocks().Block(hash)
+		if err != nil {
+			return err
+		}
+
+		count := 0
+		err = tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		block := base.Serialize()
+		header := make([]byte, 0, len(snapshotMagic)+1+4+len(block)+8)
+		header = append(header, snapshotMagic...)
+		header = append(header, snapshotVersion)
+		header = binary.BigEndian.AppendUint32(header, uint32(len(block)))
+		header = append(header, block...)
+		header = binary.BigEndian.AppendUint64(header, uint64(count))
+		_, err = bw.Write(header)
+		if err != nil {
+			return err
+		}
+
+		h := newUTXOHasher()
+		err = tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+			h.add(op, out)
+			_, err := bw.Write(encodeSnapshotEntry(op, out))
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		stats = h.stats()
+		stats.BestBlock, stats.Height = hash, height
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, bw.Flush()
+}
+
+// encodeSnapshotEntry encodes an output as the length-prefixed txid, the
+// 4-byte index, the 8-byte value and the length-prefixed public key hash
+func encodeSnapshotEntry(op OutPoint, out TxOutput) []byte {
+	d := make([]byte, 0, 1+len(op.Txid)+4+8+4+len(out.PubKeyHash))
+	d = append(d, byte(len(op.Txid)))
+	d = append(d, op.Txid...)
+	d = binary.BigEndian.AppendUint32(d, uint32(op.Vout))
+	d = binary.BigEndian.AppendUint64(d, uint64(out.Value))
+	d = binary.BigEndian.AppendUint32(d, out.scriptHeader())
+
+	return append(d, out.PubKeyHash...)
+}
+
+func readSnapshotEntry(r *bufio.Reader) (OutPoint, TxOutput, error) {
+	n, err := r.ReadByte()
+	if err != nil {
+		return OutPoint{}, TxOutput{}, err
+	}
+	txid := make([]byte, n)
+	_, err = io.ReadFull(r, txid)
+	if err != nil {
+		return OutPoint{}, TxOutput{}, err
+	}
+
+	var fixed [16]byte
+	_, err = io.ReadFull(r, fixed[:])
+	if err != nil {
+		return OutPoint{}, TxOutput{}, err
+	}
+	keyType := wallet.KeyType(fixed[12])
+	pkhLen := binary.BigEndian.Uint32(fixed[12:]) & 0xffffff
+	if pkhLen > 1024 {
+		return OutPoint{}, TxOutput{}, fmt.Errorf("%w: output script of %d bytes", ErrInvalidSnapshot, pkhLen)
+	}
+	pkh := make([]byte, pkhLen)
+	_, err = io.ReadFull(r, pkh)
+	if err != nil {
+		return OutPoint{}, TxOutput{}, err
+	}
+
+	op := OutPoint{txid, int(binary.BigEndian.Uint32(fixed[0:]))}
+	out := TxOutput{int(binary.BigEndian.Uint64(fixed[4:])), pkh, keyType}
+
+	return op, out, nil
+}
+
+// LoadUTXOSnapshot starts a chain in an empty store from a snapshot written
+// by DumpUTXOSnapshot. The snapshot must be pinned in the parameters, the
+// outputs it holds must hash to the pinned hash. The blocks before the base
+// block, and the undo data of the base block, are missing until
+// ValidateSnapshot checks them.
+func LoadUTXOSnapshot(store Store, r io.Reader, params *chaincfg.Params) (*Blockchain, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(snapshotMagic)+1+4)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) || header[len(snapshotMagic)] != snapshotVersion {
+		return nil, fmt.Errorf("%w: not a version %d snapshot", ErrInvalidSnapshot, snapshotVersion)
+	}
+
+	blockLen := binary.BigEndian.Uint32(header[len(snapshotMagic)+1:])
+	if blockLen > maxBlockFileSize {
+		return nil, fmt.Errorf("%w: base block of %d bytes", ErrInvalidSnapshot, blockLen)
+	}
+	d := make([]byte, blockLen+8)
+	_, err = io.ReadFull(br, d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	base, err := DeserializeBlock(d[:blockLen])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSnapshot, err)
+	}
+	count := binary.BigEndian.Uint64(d[blockLen:])
+
+	pinned, ok := params.AssumedUTXO(hex.EncodeToString(base.Hash))
+	if !ok {
+		return nil, fmt.Errorf("%w: block %x is not pinned on %s", ErrInvalidSnapshot, base.Hash, params.Name)
+	}
+	if base.Height != pinned.Height || !NewProofOfWork(base, params.TargetBits).Validate() {
+		return nil, fmt.Errorf("%w: bad base block %x", ErrInvalidSnapshot, base.Hash)
+	}
+
+	err = store.Update(func(tx StoreTx) error {
+		if tx.State().Tip() != nil {
+			return errors.New("blockchain already exists")
+		}
+
+		h := newUTXOHasher()
+		for i := uint64(0); i < count; i++ {
+			op, out, err := readSnapshotEntry(br)
+			if err != nil {
+				return fmt.Errorf("%w: output %d: %v", ErrInvalidSnapshot, i, err)
+			}
+			h.add(op, out)
+			err = tx.UTXO().PutOutput(op, out)
+			if err != nil {
+				return err
+			}
+		}
+		if hex.EncodeToString(h.stats().Hash) != pinned.SetHash {
+			return fmt.Errorf("%w: UTXO set hash %x is not the pinned %s", ErrInvalidSnapshot, h.stats().Hash, pinned.SetHash)
+		}
+
+		err := tx.Blocks().PutBlock(base)
+		if err != nil {
+			return err
+		}
+		for _, t := range base.Transactions {
+			err = tx.TxIndex().Put(t.ID, base.Hash)
+			if err != nil {
+				return err
+			}
+		}
+		err = tx.UTXO().SetBestBlock(base.Hash, base.Height)
+		if err != nil {
+			return err
+		}
+		err = tx.State().SetSnapshotBase(base.Hash)
+		if err != nil {
+			return err
+		}
+
+		return tx.State().SetTip(base.Hash)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newBlockchain(store, base.Hash, params), nil
+}
+
+// SnapshotBase returns the block the chain was loaded from a snapshot at,
+// nil if it was not or its history has been validated
+func (bc *Blockchain) SnapshotBase() ([]byte, error) {
+	var base []byte
+
+	err := bc.store.View(func(tx StoreTx) error {
+		base = tx.State().SnapshotBase()
+		return nil
+	})
+
+	return base, err
+}
+
+// ValidateSnapshot replays the blocks of history up to the snapshot base
+// block of the chain, checking their proof of work, heights and
+// transactions, and compares the UTXO set it ends up with to the pinned
+// hash. The blocks are replayed in batches, each batch is added to the
+// chain's store along with its undo data in its own transaction, the
+// replayed UTXO set is the only thing kept in memory. The snapshot base
+// is cleared once the hashes match. The tip of the chain is not touched,
+// so a node may keep extending the chain while it runs.
+func (bc *Blockchain) ValidateSnapshot(history Store) error {
+	return bc.validateSnapshot(history, reindexBatchSize)
+}
+
+func (bc *Blockchain) validateSnapshot(history Store, batchSize int) error {
+	base, err := bc.SnapshotBase()
+	if err != nil {
+		return err
+	}
+	if base == nil {
+		return fmt.Errorf("chain was not loaded from an unvalidated snapshot")
+	}
+	pinned, ok := bc.params.AssumedUTXO(hex.EncodeToString(base))
+	if !ok {
+		return fmt.Errorf("%w: block %x is not pinned on %s", ErrInvalidSnapshot, base, bc.params.Name)
+	}
+
+	// the blocks up to the base block, walking the headers back from it
+	var hashes [][]byte
+	iter := &BlockchainIterator{base, history}
+	for len(iter.CurrentHash()) != 0 {
+		hashes = append(hashes, iter.CurrentHash())
+		_, err := iter.NextHeader()
+		if err != nil {
+			return err
+		}
+	}
+
+	// the UTXO set is replayed in a memory store without a cache
+	replay := newBlockchain(NewMemoryStore(), nil, bc.params)
+	replay.utxo = nil
+	var prev *Block
+	for len(hashes) != 0 {
+		n := batchSize
+		if n > len(hashes) {
+			n = len(hashes)
+		}
+		batch := hashes[len(hashes)-n:]
+		hashes = hashes[:len(hashes)-n]
+
+		err := bc.store.Update(func(tx StoreTx) error {
+			for i := len(batch) - 1; i >= 0; i-- {
+				var block *Block
+				err := history.View(func(htx StoreTx) error {
+					var err error
+					block, err = htx.Blocks().Block(batch[i])
+					return err
+				})
+				if err != nil {
+					return err
+				}
+
+				err = replay.replayBlock(tx, prev, block)
+				if err != nil {
+					return fmt.Errorf("block %x: %w", block.Hash, err)
+				}
+				// the base block is stored already, without its undo data
+				if !bytes.Equal(block.Hash, base) {
+					err = tx.Blocks().PutBlock(block)
+					if err != nil {
+						return err
+					}
+				}
+				prev = block
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	stats, err := UTxOSet{replay}.Stats()
+	if err != nil {
+		return err
+	}
+	if stats.Height != pinned.Height || hex.EncodeToString(stats.Hash) != pinned.SetHash {
+		return fmt.Errorf("%w: history ends with UTXO set %x at height %d", ErrInvalidSnapshot, stats.Hash, stats.Height)
+	}
+
+	return bc.store.Update(func(tx StoreTx) error {
+		return tx.State().SetSnapshotBase(nil)
+	})
+}
+
+// replayBlock checks a block on top of prev, nil for the genesis block,
+// and applies it to the UTXO set of bc, the chain replaying the history.
+// The undo data and the transaction index go to tx, a store transaction
+// of the chain being validated.
+func (bc *Blockchain) replayBlock(tx StoreTx, prev, block *Block) error {
+	if !NewProofOfWork(block, bc.params.TargetBits).Validate() {
+		return errors.New("invalid proof of work")
+	}
+	height := 0
+	var prevHash []byte
+	if prev != nil {
+		height, prevHash = prev.Height+1, prev.Hash
+	} else if len(bc.params.GenesisHash) != 0 && hex.EncodeToString(block.Hash) != bc.params.GenesisHash {
+		return fmt.Errorf("%w: the history starts from another block", ErrGenesisMismatch)
+	}
+	if !bytes.Equal(block.PrevBlockHash, prevHash) {
+		return fmt.Errorf("the block does not extend %x", prevHash)
+	}
+	if block.Height != height {
+		return fmt.Errorf("height %d, want %d: %w", block.Height, height, ErrInvalidHeight)
+	}
+
+	err := bc.checkTransactions(block.Transactions, block.Height)
+	if err != nil {
+		return err
+	}
+
+	return bc.store.Update(func(rtx StoreTx) error {
+		return applyBlock(replayTx{tx, rtx.UTXO()}, block)
+	})
+}
+
+// replayTx a store transaction of the chain whose UTXO set is the one
+// ValidateSnapshot replays
+type replayTx struct {
+	StoreTx
+	utxo UTXOStore
+}
+
+func (t replayTx) UTXO() UTXOStore {
+	return t.utxo
+}
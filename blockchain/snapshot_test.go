@@ -0,0 +1,177 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+func TestUTXOSnapshot(t *testing.T) {
+	params := *privateParams()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromAddr, toAddr := string(from.Address(&params)), string(to.Address(&params))
+
+	full, err := CreateBlockchain(NewMemoryStore(), fromAddr, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spend, err := NewUTXOTransaction(from, toAddr, 10, &UTxOSet{full})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(fromAddr, "", 1, 0, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := full.AddBlock([]*Transaction{cb, spend}); err != nil {
+		t.Fatal(err)
+	}
+	cb, err = NewCoinbaseTX(fromAddr, "", 2, 0, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := full.AddBlock([]*Transaction{cb}); err != nil {
+		t.Fatal(err)
+	}
+
+	var snapshot bytes.Buffer
+	stats, err := full.DumpUTXOSnapshot(&snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Height != 2 || stats.Outputs != 4 {
+		t.Fatalf("snapshot stats = %+v", stats)
+	}
+
+	// a snapshot that is not pinned is refused
+	if _, err := LoadUTXOSnapshot(NewMemoryStore(), bytes.NewReader(snapshot.Bytes()), &params); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("load of an unpinned snapshot: %v", err)
+	}
+	params.AssumeUTXO = []chaincfg.AssumeUTXO{{
+		Height:    stats.Height,
+		BlockHash: hex.EncodeToString(stats.BestBlock),
+		SetHash:   hex.EncodeToString(stats.Hash),
+	}}
+
+	tampered := append([]byte{}, snapshot.Bytes()...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := LoadUTXOSnapshot(NewMemoryStore(), bytes.NewReader(tampered), &params); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("load of a tampered snapshot: %v", err)
+	}
+
+	bc, err := LoadUTXOSnapshot(NewMemoryStore(), &snapshot, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := UTxOSet{bc}.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(loaded.Hash, stats.Hash) || !bytes.Equal(loaded.BestBlock, stats.BestBlock) {
+		t.Errorf("loaded stats = %+v, want %+v", loaded, stats)
+	}
+
+	// the snapshot outputs can be spent before the history is validated
+	back, err := NewUTXOTransaction(to, fromAddr, 5, &UTxOSet{bc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err = NewCoinbaseTX(fromAddr, "", 3, 0, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, back}); err != nil {
+		t.Fatal(err)
+	}
+	genesisCoinbase := genesisBlock(t, full).Transactions[0].ID
+	if _, err := bc.FindTransaction(genesisCoinbase); !errors.Is(err, ErrNotFound) {
+		t.Errorf("transaction before the snapshot: %v", err)
+	}
+
+	// batches of two blocks, the history has three
+	if err := bc.validateSnapshot(full.store, 2); err != nil {
+		t.Fatal(err)
+	}
+	if base, _ := bc.SnapshotBase(); base != nil {
+		t.Errorf("snapshot base %x after validation", base)
+	}
+	if _, err := bc.FindTransaction(genesisCoinbase); err != nil {
+		t.Errorf("transaction before the snapshot after validation: %v", err)
+	}
+
+	// the validated blocks have their undo data
+	issued, err := bc.IssuedSupply()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued != params.Emission.Supply(3) {
+		t.Errorf("issued supply %d, want %d", issued, params.Emission.Supply(3))
+	}
+	for height := 3; height > 0; height-- {
+		if _, err := bc.DisconnectTip(); err != nil {
+			t.Fatalf("disconnect block %d: %v", height, err)
+		}
+	}
+	total, err := UTxOSet{bc}.TotalAmount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != params.Emission.Supply(0) {
+		t.Errorf("UTXO set total %d at the genesis block, want %d", total, params.Emission.Supply(0))
+	}
+}
+
+func TestPinnedSnapshots(t *testing.T) {
+	for _, params := range chaincfg.Networks {
+		full, err := CreateBlockchain(NewMemoryStore(), "", params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var snapshot bytes.Buffer
+		stats, err := full.DumpUTXOSnapshot(&snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pinned, ok := params.AssumedUTXO(hex.EncodeToString(stats.BestBlock))
+		if !ok || pinned.Height != stats.Height || pinned.SetHash != hex.EncodeToString(stats.Hash) {
+			t.Errorf("%s: snapshot %+v, pinned %+v", params.Name, stats, pinned)
+			continue
+		}
+
+		bc, err := LoadUTXOSnapshot(NewMemoryStore(), &snapshot, params)
+		if err != nil {
+			t.Fatalf("%s: %v", params.Name, err)
+		}
+		if err := bc.ValidateSnapshot(full.store); err != nil {
+			t.Errorf("%s: %v", params.Name, err)
+		}
+		if _, err := NewBlockchain(bc.store, params); err != nil {
+			t.Errorf("%s: validated chain: %v", params.Name, err)
+		}
+	}
+}
+
+func genesisBlock(t *testing.T, bc *Blockchain) *Block {
+	iter := bc.Iterator()
+	for {
+		block, err := iter.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(block.PrevBlockHash) == 0 {
+			return block
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Store persists the blocks, the chain state and the UTXO set of a chain.
+// Reads and writes happen in transactions, the changes made by an Update
+// are applied together or not at all.
+type Store interface {
+	View(fn func(tx StoreTx) error) error
+	Update(fn func(tx StoreTx) error) error
+	Close() error
+}
+
+// StoreTx gives access to the data of a store within a transaction
+type StoreTx interface {
+	Blocks() BlockStore
+	State() ChainState
+	UTXO() UTXOStore
+	TxIndex() TxIndex
+}
+
+// BlockStore stores blocks by their hash, along with the undo data
+// needed to disconnect them
+type BlockStore interface {
+	// Block returns ErrNotFound if there is no block with the hash,
+	// and ErrPruned if its transactions have been pruned
+	Block(hash []byte) (*Block, error)
+	// Header returns the block without its transactions,
+	// it is kept when the block is pruned
+	Header(hash []byte) (*Block, error)
+	PutBlock(block *Block) error
+	// Undo returns the outputs spent by the block,
+	// ErrNotFound if there is no undo data for it
+	Undo(hash []byte) ([]SpentOutput, error)
+	PutUndo(hash []byte, spent []SpentOutput) error
+}
+
+// SpentOutput an output spent by a block
+type SpentOutput struct {
+	OutPoint OutPoint
+	Output   TxOutput
+}
+
+// ChainState records the tip of the chain
+type ChainState interface {
+	// Tip returns nil if the store holds no chain yet
+	Tip() []byte
+	SetTip(hash []byte) error
+	// SnapshotBase returns the block a UTXO snapshot was loaded at,
+	// nil once the blocks before it have been validated
+	SnapshotBase() []byte
+	// SetSnapshotBase records the base block, nil clears it
+	SetSnapshotBase(hash []byte) error
+}
+
+// OutPoint identifies an output of a transaction
+type OutPoint struct {
+	Txid []byte
+	Vout int
+}
+
+func (op OutPoint) String() string {
+	return fmt.Sprintf("%x:%d", op.Txid, op.Vout)
+}
+
+// UTXOStore stores the unspent transaction outputs by outpoint, along
+// with the block the set is up to date with
+type UTXOStore interface {
+	// Output returns ErrNotFound if the output is spent or does not exist
+	Output(op OutPoint) (TxOutput, error)
+	PutOutput(op OutPoint, out TxOutput) error
+	DeleteOutput(op OutPoint) error
+	// ForEach visits the outputs in the order of their outpoint
+	ForEach(fn func(op OutPoint, out TxOutput) error) error
+	// BestBlock returns the hash and height of the last block applied
+	// to the set, the hash is nil if there is none
+	BestBlock() ([]byte, int)
+	SetBestBlock(hash []byte, height int) error
+	// Clear removes every output and the best block
+	Clear() error
+}
+
+// TxIndex maps the IDs of transactions to the block holding them
+type TxIndex interface {
+	// BlockHash returns ErrNotFound if the transaction is not indexed
+	BlockHash(txid []byte) ([]byte, error)
+	Put(txid, blockHash []byte) error
+	Delete(txid []byte) error
+	// Clear removes every entry
+	Clear() error
+}
+
+// tipKey the key of the tip hash in the blocks bucket
+var tipKey = []byte("l")
+
+// snapshotBaseKey the key of the snapshot base block in the meta bucket
+var snapshotBaseKey = []byte("snapshotbase")
+
+// utxoBestKey the key of the UTXO set's best block in the meta bucket
+var utxoBestKey = []byte("utxobest")
+
+// pruner a store which prunes the bodies of the blocks deeper than its
+// prune depth below the tip
+type pruner interface {
+	pruneDepth() int
+}
+
+// bucket the ordered key/value primitive the backends provide,
+// *bolt.Bucket implements it
+type bucket interface {
+	Get(key []byte) []byte
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	ForEach(fn func(k, v []byte) error) error
+}
+
+// kvTx a transaction of a key/value backend, which keeps the data
+// of a chain in named buckets
+type kvTx interface {
+	bucket(name string) bucket
+	resetBucket(name string) (bucket, error)
+	blockStore() BlockStore
+}
+
+// kvStoreTx implements StoreTx on the buckets of a key/value backend
+type kvStoreTx struct {
+	tx kvTx
+}
+
+func (t kvStoreTx) Blocks() BlockStore {
+	return t.tx.blockStore()
+}
+
+func (t kvStoreTx) State() ChainState {
+	return kvState{t.tx}
+}
+
+func (t kvStoreTx) UTXO() UTXOStore {
+	return kvUTXO{t.tx}
+}
+
+func (t kvStoreTx) TxIndex() TxIndex {
+	return kvTxIndex{t.tx}
+}
+
+type kvBlocks struct {
+	tx kvTx
+}
+
+func (s kvBlocks) Block(hash []byte) (*Block, error) {
+	d := s.tx.bucket(blocksBucket).Get(hash)
+	if d == nil {
+		return nil, fmt.Errorf("block %x: %w", hash, ErrNotFound)
+	}
+
+	return DeserializeBlock(d)
+}
+
+func (s kvBlocks) Header(hash []byte) (*Block, error) {
+	block, err := s.Block(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return block.header(), nil
+}
+
+func (s kvBlocks) PutBlock(block *Block) error {
+	return s.tx.bucket(blocksBucket).Put(block.Hash, block.Serialize())
+}
+
+func (s kvBlocks) Undo(hash []byte) ([]SpentOutput, error) {
+	d := s.tx.bucket(undoBucket).Get(hash)
+	if d == nil {
+		return nil, fmt.Errorf("undo data of block %x: %w", hash, ErrNotFound)
+	}
+
+	return decodeUndo(d)
+}
+
+func (s kvBlocks) PutUndo(hash []byte, spent []SpentOutput) error {
+	return s.tx.bucket(undoBucket).Put(hash, encodeUndo(spent))
+}
+
+// encodeUndo encodes the spent outputs like the entries of a UTXO snapshot
+func encodeUndo(spent []SpentOutput) []byte {
+	var d []byte
+	for _, s := range spent {
+		d = append(d, encodeSnapshotEntry(s.OutPoint, s.Output)...)
+	}
+
+	return d
+}
+
+func decodeUndo(d []byte) ([]SpentOutput, error) {
+	var spent []SpentOutput
+
+	r := bufio.NewReader(bytes.NewReader(d))
+	for {
+		op, out, err := readSnapshotEntry(r)
+		if err == io.EOF {
+			return spent, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("malformed undo data: %v", err)
+		}
+		spent = append(spent, SpentOutput{op, out})
+	}
+}
+
+type kvState struct {
+	tx kvTx
+}
+
+func (s kvState) Tip() []byte {
+	return copyBytes(s.tx.bucket(blocksBucket).Get(tipKey))
+}
+
+func (s kvState) SetTip(hash []byte) error {
+	return s.tx.bucket(blocksBucket).Put(tipKey, hash)
+}
+
+func (s kvState) SnapshotBase() []byte {
+	return copyBytes(s.tx.bucket(metaBucket).Get(snapshotBaseKey))
+}
+
+func (s kvState) SetSnapshotBase(hash []byte) error {
+	if hash == nil {
+		return s.tx.bucket(metaBucket).Delete(snapshotBaseKey)
+	}
+
+	return s.tx.bucket(metaBucket).Put(snapshotBaseKey, hash)
+}
+
+type kvUTXO struct {
+	tx kvTx
+}
+
+// outPointKey the key of an outpoint: the txid followed by the big-endian
+// index, so that the outputs of a transaction are stored next to each other
+func outPointKey(op OutPoint) []byte {
+	key := make([]byte, len(op.Txid)+4)
+	copy(key, op.Txid)
+	binary.BigEndian.PutUint32(key[len(op.Txid):], uint32(op.Vout))
+
+	return key
+}
+
+func decodeOutPointKey(key []byte) (OutPoint, error) {
+	if len(key) < 4 {
+		return OutPoint{}, errors.New("malformed outpoint key")
+	}
+	n := len(key) - 4
+
+	return OutPoint{copyBytes(key[:n]), int(binary.BigEndian.Uint32(key[n:]))}, nil
+}
+
+func (s kvUTXO) Output(op OutPoint) (TxOutput, error) {
+	d := s.tx.bucket(utxoBucket).Get(outPointKey(op))
+	if d == nil {
+		return TxOutput{}, fmt.Errorf("output %x:%d: %w", op.Txid, op.Vout, ErrNotFound)
+	}
+
+	return DeserializeOutput(d)
+}
+
+func (s kvUTXO) PutOutput(op OutPoint, out TxOutput) error {
+	return s.tx.bucket(utxoBucket).Put(outPointKey(op), out.Serialize())
+}
+
+func (s kvUTXO) DeleteOutput(op OutPoint) error {
+	return s.tx.bucket(utxoBucket).Delete(outPointKey(op))
+}
+
+func (s kvUTXO) ForEach(fn func(op OutPoint, out TxOutput) error) error {
+	return s.tx.bucket(utxoBucket).ForEach(func(k, v []byte) error {
+		op, err := decodeOutPointKey(k)
+		if err != nil {
+			return err
+		}
+		out, err := DeserializeOutput(v)
+		if err != nil {
+			return err
+		}
+
+		return fn(op, out)
+	})
+}
+
+// BestBlock decodes the big-endian height followed by the hash
+func (s kvUTXO) BestBlock() ([]byte, int) {
+	d := s.tx.bucket(metaBucket).Get(utxoBestKey)
+	if len(d) < 8 {
+		return nil, 0
+	}
+
+	return copyBytes(d[8:]), int(binary.BigEndian.Uint64(d))
+}
+
+func (s kvUTXO) SetBestBlock(hash []byte, height int) error {
+	d := make([]byte, 8+len(hash))
+	binary.BigEndian.PutUint64(d, uint64(height))
+	copy(d[8:], hash)
+
+	return s.tx.bucket(metaBucket).Put(utxoBestKey, d)
+}
+
+func (s kvUTXO) Clear() error {
+	_, err := s.tx.resetBucket(utxoBucket)
+	if err != nil {
+		return err
+	}
+
+	return s.tx.bucket(metaBucket).Delete(utxoBestKey)
+}
+
+type kvTxIndex struct {
+	tx kvTx
+}
+
+func (s kvTxIndex) BlockHash(txid []byte) ([]byte, error) {
+	hash := s.tx.bucket(txIndexBucket).Get(txid)
+	if hash == nil {
+		return nil, fmt.Errorf("transaction %x: %w", txid, ErrNotFound)
+	}
+
+	return copyBytes(hash), nil
+}
+
+func (s kvTxIndex) Put(txid, blockHash []byte) error {
+	return s.tx.bucket(txIndexBucket).Put(txid, blockHash)
+}
+
+func (s kvTxIndex) Delete(txid []byte) error {
+	return s.tx.bucket(txIndexBucket).Delete(txid)
+}
+
+func (s kvTxIndex) Clear() error {
+	_, err := s.tx.resetBucket(txIndexBucket)
+	return err
+}
+
+// copyBytes copies a value that is only valid during a transaction
+func copyBytes(d []byte) []byte {
+	if d == nil {
+		return nil
+	}
+
+	return append([]byte{}, d...)
+}
@@ -0,0 +1,173 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func testStores(t *testing.T, fn func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		fn(t, NewMemoryStore())
+	})
+	t.Run("bolt", func(t *testing.T) {
+		store, err := OpenBoltStore(filepath.Join(t.TempDir(), "block-chain.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		fn(t, store)
+	})
+	t.Run("flatfile", func(t *testing.T) {
+		dir := t.TempDir()
+		store, err := OpenFlatFileStore(filepath.Join(dir, "block-chain.db"), filepath.Join(dir, "blocks"), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer store.Close()
+		fn(t, store)
+	})
+}
+
+func TestStore(t *testing.T) {
+	testStores(t, func(t *testing.T, store Store) {
+		block := &Block{Hash: []byte("block"), Height: 7}
+		out := TxOutput{Value: 10, PubKeyHash: []byte("pkh")}
+		op := func(id string, vout int) OutPoint {
+			return OutPoint{[]byte(id), vout}
+		}
+
+		err := store.Update(func(tx StoreTx) error {
+			if tx.State().Tip() != nil {
+				t.Error("empty store has a tip")
+			}
+			if err := tx.Blocks().PutBlock(block); err != nil {
+				return err
+			}
+			if err := tx.State().SetTip(block.Hash); err != nil {
+				return err
+			}
+			for _, o := range []OutPoint{op("c", 0), op("a", 256), op("b", 0), op("a", 1)} {
+				if err := tx.UTXO().PutOutput(o, out); err != nil {
+					return err
+				}
+			}
+			if err := tx.TxIndex().Put([]byte("c"), block.Hash); err != nil {
+				return err
+			}
+			if err := tx.UTXO().SetBestBlock(block.Hash, block.Height); err != nil {
+				return err
+			}
+			return tx.UTXO().DeleteOutput(op("b", 0))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a failed update leaves no trace
+		failure := errors.New("failure")
+		err = store.Update(func(tx StoreTx) error {
+			tx.UTXO().PutOutput(op("d", 0), out)
+			tx.UTXO().SetBestBlock([]byte("other"), 8)
+			tx.State().SetTip([]byte("other"))
+			return failure
+		})
+		if err != failure {
+			t.Fatalf("update returned %v", err)
+		}
+
+		err = store.View(func(tx StoreTx) error {
+			if !bytes.Equal(tx.State().Tip(), block.Hash) {
+				t.Errorf("tip = %s", tx.State().Tip())
+			}
+			got, err := tx.Blocks().Block(block.Hash)
+			if err != nil {
+				return err
+			}
+			if got.Height != block.Height {
+				t.Errorf("block height = %d", got.Height)
+			}
+			if _, err := tx.Blocks().Block([]byte("missing")); !errors.Is(err, ErrNotFound) {
+				t.Errorf("missing block: %v", err)
+			}
+
+			var ops []string
+			tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+				ops = append(ops, fmt.Sprintf("%s:%d", op.Txid, op.Vout))
+				return nil
+			})
+			if fmt.Sprint(ops) != "[a:1 a:256 c:0]" {
+				t.Errorf("UTXO outpoints = %v", ops)
+			}
+			if hash, height := tx.UTXO().BestBlock(); !bytes.Equal(hash, block.Hash) || height != block.Height {
+				t.Errorf("UTXO best block = %s at %d", hash, height)
+			}
+			if hash, err := tx.TxIndex().BlockHash([]byte("c")); err != nil || !bytes.Equal(hash, block.Hash) {
+				t.Errorf("indexed block = %s, %v", hash, err)
+			}
+
+			if tx.UTXO().PutOutput(op("e", 0), out) == nil {
+				t.Error("write in a read-only transaction")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = store.Update(func(tx StoreTx) error {
+			return tx.UTXO().Clear()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.View(func(tx StoreTx) error {
+			if _, err := tx.UTXO().Output(op("a", 1)); !errors.Is(err, ErrNotFound) {
+				t.Errorf("cleared output: %v", err)
+			}
+			if hash, _ := tx.UTXO().BestBlock(); hash != nil {
+				t.Error("cleared UTXO set has a best block")
+			}
+			return nil
+		})
+	})
+}
+
+func TestUTXOStats(t *testing.T) {
+	var hashes []string
+
+	testStores(t, func(t *testing.T, store Store) {
+		err := store.Update(func(tx StoreTx) error {
+			for i, id := range []string{"b", "a", "b"} {
+				out := TxOutput{Value: 10 * (i + 1), PubKeyHash: []byte("pkh")}
+				if err := tx.UTXO().PutOutput(OutPoint{[]byte(id), i}, out); err != nil {
+					return err
+				}
+			}
+			return tx.UTXO().SetBestBlock([]byte("best"), 3)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := UTxOSet{&Blockchain{store: store}}.Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(stats.BestBlock) != "best" || stats.Height != 3 {
+			t.Errorf("best block = %s at %d", stats.BestBlock, stats.Height)
+		}
+		if stats.Transactions != 2 || stats.Outputs != 3 || stats.TotalAmount != 60 {
+			t.Errorf("stats = %+v", stats)
+		}
+		hashes = append(hashes, fmt.Sprintf("%x", stats.Hash))
+	})
+
+	for _, h := range hashes[1:] {
+		if h != hashes[0] {
+			t.Errorf("UTXO set hashes differ between stores: %v", hashes)
+		}
+	}
+}
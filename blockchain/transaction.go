@@ -0,0 +1,378 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// Transaction stores inputs and outputs
+type Transaction struct {
+	ID   []byte
+	Vin  []TxInput
+	Vout []TxOutput
+}
+
+// NewCoinbaseTX create the coinbase transaction of the block at height,
+// which pays the block subsidy and the fees of the block's transactions
+// to the given address
+func NewCoinbaseTX(to, data string, height, fees int, params *chaincfg.Params) (*Transaction, error) {
+	if len(data) == 0 {
+		data = fmt.Sprintf("Reward to %s", to)
+	}
+
+	tout, err := NewTxOutput(params.Emission.Subsidy(height)+fees, to, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCoinbase(height, data, *tout), nil
+}
+
+// newCoinbase creates a coinbase transaction with a single output
+func newCoinbase(height int, data string, out TxOutput) *Transaction {
+	// the coinbase data starts with the block height, so coinbase
+	// transactions paying the same address never share an ID
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, uint64(height))
+
+	tin := TxInput{[]byte{}, -1, nil, append(heightBytes, data...)}
+	tx := Transaction{Vin: []TxInput{tin}, Vout: []TxOutput{out}}
+	tx.ID = tx.Hash()
+
+	return &tx
+}
+
+// Payment an amount paid to an address
+type Payment struct {
+	Address string
+	Amount  int
+}
+
+// CoinControl picks the outputs a transaction spends and where its change
+// goes
+type CoinControl struct {
+	// Inputs the outputs to spend, all of them and only them. None lets
+	// the outputs of the sender be selected.
+	Inputs []OutPoint
+	// Locked the outputs not to spend, by outpoint
+	Locked map[string]bool
+	// Change the address the change goes to, the sender's when empty
+	Change string
+	// Selector selects the outputs when no inputs are given, nil uses
+	// DefaultCoinSelector
+	Selector CoinSelector
+	// Fees the fee rates the transaction pays, its fee is what the inputs
+	// pay beyond the outputs
+	Fees Fees
+}
+
+// NewUTXOTransaction create a transaction sending amount from the spender,
+// a wallet or a redeem script, to an address
+func NewUTXOTransaction(s wallet.Spender, to string, amount int, u *UTxOSet) (*Transaction, error) {
+	return NewSendTransaction(s, []Payment{{to, amount}}, nil, u)
+}
+
+// NewSendTransaction create a transaction paying every payment from the
+// spender, the coin control may be nil
+func NewSendTransaction(s wallet.Spender, payments []Payment, cc *CoinControl, u *UTxOSet) (*Transaction, error) {
+	keyType, pubKeyHash := s.Lock()
+	tx, err := NewUnsignedTransaction(keyType, pubKeyHash, payments, cc, u)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tx.Vin {
+		tx.Vin[i].PubKey = s.Unlock()
+	}
+	err = u.BC.SignTransaction(tx, s, SigHashAll)
+	if err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// NewUnsignedTransaction create a transaction paying every payment and
+// its fee from the outputs locked to the public key hash the coin control
+// selects, the change going back to them unless the coin control names a
+// change address. Its inputs neither reveal a key nor are signed, which
+// does not change its ID.
+func NewUnsignedTransaction(keyType wallet.KeyType, pubKeyHash []byte, payments []Payment, cc *CoinControl, u *UTxOSet) (*Transaction, error) {
+	if cc == nil {
+		cc = &CoinControl{}
+	}
+	if len(payments) == 0 {
+		return nil, errors.New("no payment")
+	}
+
+	var outputs []TxOutput
+	amount := 0
+	for _, p := range payments {
+		if p.Amount <= 0 {
+			return nil, fmt.Errorf("payment of %d to %s", p.Amount, p.Address)
+		}
+		output, err := NewTxOutput(p.Amount, p.Address, u.BC.params)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, *output)
+		amount += p.Amount
+	}
+
+	// the target covers the fee of everything but the inputs and change
+	target := amount + fee(txBaseSize+len(outputs)*txOutputSize, cc.Fees.Rate)
+	selected, err := u.selectOutputs(keyType, pubKeyHash, target, cc)
+	if err != nil {
+		return nil, err
+	}
+	value := 0
+	var inputs []TxInput
+	for _, uo := range selected {
+		value += cc.Fees.EffectiveValue(uo.Output)
+		inputs = append(inputs, TxInput{uo.OutPoint.Txid, uo.OutPoint.Vout, nil, nil})
+	}
+	if value < target {
+		return nil, fmt.Errorf("%w: left %d", ErrInsufficientFunds, value)
+	}
+
+	// an excess not worth the cost of change is given up to the fee
+	if excess := value - target; excess > cc.Fees.ChangeCost() {
+		change := TxOutput{excess - cc.Fees.OutputFee(), pubKeyHash, keyType}
+		if len(cc.Change) != 0 {
+			err := change.Lock(cc.Change, u.BC.params)
+			if err != nil {
+				return nil, fmt.Errorf("change: %w", err)
+			}
+		}
+		outputs = append(outputs, change)
+	}
+
+	tx := Transaction{nil, inputs, outputs}
+	tx.ID = tx.Hash()
+
+	return &tx, nil
+}
+
+// IsCoinbase ...
+func (t *Transaction) IsCoinbase() bool {
+	return len(t.Vin) == 1 && len(t.Vin[0].Txid) == 0 && t.Vin[0].Vout == -1
+}
+
+// OutputValue returns the sum of the transaction's outputs
+func (t *Transaction) OutputValue() int {
+	value := 0
+	for _, out := range t.Vout {
+		value += out.Value
+	}
+
+	return value
+}
+
+// TrimmedCopy ...
+func (t *Transaction) TrimmedCopy() Transaction {
+	var inputs []TxInput
+	var outputs []TxOutput
+	for _, input := range t.Vin {
+		inputs = append(inputs, TxInput{input.Txid, input.Vout, nil, nil})
+	}
+	for _, output := range t.Vout {
+		outputs = append(outputs, TxOutput{output.Value, output.PubKeyHash, output.KeyType})
+	}
+
+	return Transaction{t.ID, inputs, outputs}
+}
+
+// Hash returns the ID of the transaction: the hash of its data without
+// the witnesses, the signatures and the public keys or scripts of its
+// inputs. Re-encoding a signature does not change it. The data of a
+// coinbase input is not a witness and is kept.
+func (t *Transaction) Hash() []byte {
+	copyTx := *t
+	if !t.IsCoinbase() {
+		copyTx = t.TrimmedCopy()
+	}
+	copyTx.ID = []byte{}
+
+	hash := sha256.Sum256(copyTx.Serialize())
+
+	return hash[:]
+}
+
+// WitnessHash returns the hash of the whole transaction, witnesses
+// included. It is the ID of a coinbase transaction.
+func (t *Transaction) WitnessHash() []byte {
+	copyTx := *t
+	copyTx.ID = []byte{}
+
+	hash := sha256.Sum256(copyTx.Serialize())
+
+	return hash[:]
+}
+
+// Sign signs every input with the hash type, prevOuts holds the outputs
+// the inputs spend by their outpoint
+func (t *Transaction) Sign(s wallet.Spender, prevOuts map[string]TxOutput, hashType SigHashType) error {
+	if t.IsCoinbase() {
+		return nil
+	}
+
+	err := t.checkPrevOuts(prevOuts)
+	if err != nil {
+		return err
+	}
+
+	for index, in := range t.Vin {
+		err := t.SignInput(index, s, prevOuts[in.OutPoint().String()], hashType)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SignInput signs the input at index, which spends prevOut, with the hash
+// type. The signature data is the one of the spender and the hash type.
+func (t *Transaction) SignInput(index int, s wallet.Spender, prevOut TxOutput, hashType SigHashType) error {
+	hash, err := t.sigHash(index, prevOut, hashType)
+	if err != nil {
+		return err
+	}
+	sig, err := s.Sign(hash)
+	if err != nil {
+		return err
+	}
+
+	t.Vin[index].Signature = append(sig, byte(hashType))
+
+	return nil
+}
+
+// Verify checks every input is signed by the owner of the output it spends,
+// it returns ErrInvalidSignature otherwise
+func (t *Transaction) Verify(prevOuts map[string]TxOutput) error {
+	checks, err := t.sigChecks(prevOuts)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range checks {
+		err := c.verify(nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Fee returns what the inputs of the transaction pay beyond its outputs,
+// prevOuts holds the outputs the inputs spend by their outpoint. It
+// returns ErrInvalidValue when an output is negative or the outputs
+// exceed the inputs. A coinbase pays no fee.
+func (t *Transaction) Fee(prevOuts map[string]TxOutput) (int, error) {
+	in := 0
+	if !t.IsCoinbase() {
+		err := t.checkPrevOuts(prevOuts)
+		if err != nil {
+			return 0, err
+		}
+		for _, vin := range t.Vin {
+			in += prevOuts[vin.OutPoint().String()].Value
+		}
+	}
+
+	out := 0
+	for index, output := range t.Vout {
+		if output.Value < 0 {
+			return 0, fmt.Errorf("transaction %x output %d of %d: %w", t.ID, index, output.Value, ErrInvalidValue)
+		}
+		// compared before adding, so the sum cannot overflow
+		if !t.IsCoinbase() && output.Value > in-out {
+			return 0, fmt.Errorf("transaction %x pays more than its inputs %d: %w", t.ID, in, ErrInvalidValue)
+		}
+		out += output.Value
+	}
+	if t.IsCoinbase() {
+		return 0, nil
+	}
+
+	return in - out, nil
+}
+
+// sigChecks returns the signature checks of the transaction's inputs,
+// after checking its values
+func (t *Transaction) sigChecks(prevOuts map[string]TxOutput) ([]sigCheck, error) {
+	if t.IsCoinbase() {
+		return nil, nil
+	}
+
+	_, err := t.Fee(prevOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]sigCheck, len(t.Vin))
+	for index, in := range t.Vin {
+		checks[index] = sigCheck{t, index, prevOuts[in.OutPoint().String()]}
+	}
+
+	return checks, nil
+}
+
+// checkPrevOuts makes sure the output of every input is known
+func (t *Transaction) checkPrevOuts(prevOuts map[string]TxOutput) error {
+	for _, vin := range t.Vin {
+		op := vin.OutPoint()
+		if _, ok := prevOuts[op.String()]; !ok {
+			return fmt.Errorf("output %s: %w", op, ErrNotFound)
+		}
+	}
+
+	return nil
+}
+
+// Serialize ...
+func (t Transaction) Serialize() []byte {
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	err := enc.Encode(t)
+	if err != nil {
+		// a transaction holds no types gob cannot encode
+		panic(err)
+	}
+	return buf.Bytes()
+}
+
+// String returns a human-readable representation of a transaction
+func (t Transaction) String() string {
+	var lines []string
+
+	lines = append(lines, fmt.Sprintf("--- Transaction %x:", t.ID))
+
+	for i, input := range t.Vin {
+
+		lines = append(lines, fmt.Sprintf("     Input %d:", i))
+		lines = append(lines, fmt.Sprintf("       TXID:      %x", input.Txid))
+		lines = append(lines, fmt.Sprintf("       Out:       %d", input.Vout))
+		lines = append(lines, fmt.Sprintf("       Signature: %x", input.Signature))
+		lines = append(lines, fmt.Sprintf("       PubKey:    %x", input.PubKey))
+	}
+
+	for i, output := range t.Vout {
+		lines = append(lines, fmt.Sprintf("     Output %d:", i))
+		lines = append(lines, fmt.Sprintf("       Value:  %d", output.Value))
+		lines = append(lines, fmt.Sprintf("       Script: %x", output.PubKeyHash))
+		lines = append(lines, fmt.Sprintf("       Key:    %s", output.KeyType))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -1,6 +1,10 @@
-package main
+package blockchain
 
-import "bytes"
+import (
+	"bytes"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
 
 // TxInput input of transactions
 type TxInput struct {
@@ -10,10 +14,13 @@ type TxInput struct {
 	PubKey    []byte
 }
 
+// OutPoint returns the output the input spends
+func (in *TxInput) OutPoint() OutPoint {
+	return OutPoint{in.Txid, in.Vout}
+}
+
 // CanUnlockOutputWith ...
 func (in *TxInput) CanUnlockOutputWith(unlockData []byte) bool {
-	hash := HashPublicKey(in.PubKey)
+	hash := wallet.HashPublicKey(in.PubKey)
 	return bytes.Compare(unlockData, hash) == 0
 }
-
-// func NewTxInput()
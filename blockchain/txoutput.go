@@ -0,0 +1,80 @@
+package blockchain
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// TxOutput output of transactions
+type TxOutput struct {
+	Value      int
+	PubKeyHash []byte
+	// KeyType the type of the key hashed, its signatures are checked
+	// the way of that type
+	KeyType wallet.KeyType
+}
+
+// CanUnlockedWith ...
+func (out *TxOutput) CanUnlockedWith(unlockData []byte) bool {
+	return bytes.Compare(out.PubKeyHash, unlockData) == 0
+}
+
+// Lock lock the output by given address
+// set output's publickey
+func (out *TxOutput) Lock(address string, params *chaincfg.Params) error {
+	keyType, pubKeyHash, err := wallet.DecodeAddress(address, params)
+	if err != nil {
+		return err
+	}
+	out.PubKeyHash = pubKeyHash
+	out.KeyType = keyType
+
+	return nil
+}
+
+// NewTxOutput ...
+func NewTxOutput(value int, address string, params *chaincfg.Params) (*TxOutput, error) {
+	txo := TxOutput{Value: value}
+	err := txo.Lock(address, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &txo, nil
+}
+
+// scriptHeader returns the length of the public key hash with the key
+// type in its high byte, the way snapshots, undo data and the UTXO set
+// hash encode it. P-256 outputs keep the encoding of the time before
+// key types.
+func (out TxOutput) scriptHeader() uint32 {
+	return uint32(out.KeyType)<<24 | uint32(len(out.PubKeyHash))
+}
+
+// Serialize ...
+func (out TxOutput) Serialize() []byte {
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(&buf)
+	err := encoder.Encode(out)
+	if err != nil {
+		// outputs hold no types gob cannot encode
+		panic(err)
+	}
+
+	return buf.Bytes()
+}
+
+// DeserializeOutput ...
+func DeserializeOutput(d []byte) (TxOutput, error) {
+	var out TxOutput
+	decoder := gob.NewDecoder(bytes.NewReader(d))
+
+	err := decoder.Decode(&out)
+	if err != nil {
+		return TxOutput{}, err
+	}
+	return out, nil
+}
@@ -0,0 +1,8 @@
+package blockchain
+
+import "math/big"
+
+// IntToHex conver int to bytes
+func IntToHex(i int64) []byte {
+	return big.NewInt(i).Bytes()
+}
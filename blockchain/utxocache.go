@@ -0,0 +1,377 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultUTXOCacheSize the default memory budget of the UTXO cache, in bytes
+const DefaultUTXOCacheSize = 64 << 20
+
+// utxoEntryOverhead the estimated memory an entry takes besides its key
+// and public key hash: the map slot, the entry and the slice headers
+const utxoEntryOverhead = 96
+
+// utxoCache keeps unspent outputs in memory on top of the UTXO set of a
+// store. Changes are written back in one store transaction when the cache
+// goes over its budget or gets maxLag blocks ahead of the store, at block
+// boundaries, or when the chain is closed. Until then the store's UTXO
+// set lags behind the tip, after a crash the missing blocks are applied
+// again by Reindex.
+type utxoCache struct {
+	mu      sync.RWMutex
+	entries map[string]*utxoEntry
+	usage   int
+	budget  int
+	// best the block the cached set is up to date with, nil when the
+	// cache holds no change to the store's best block
+	best       []byte
+	bestHeight int
+	// stored the height of the store's best block while the cache holds
+	// changes
+	stored int
+	// maxLag the number of blocks the cache may get ahead of the store, a
+	// pruning store must keep the blocks a crash applies again. 0 does
+	// not limit it.
+	maxLag int
+}
+
+// utxoEntry a cached output, or the spending of a stored one
+type utxoEntry struct {
+	out   TxOutput
+	spent bool
+	// dirty the store does not have the entry yet
+	dirty bool
+	// fresh the store has never held the output, so spending it
+	// only needs to drop the entry
+	fresh bool
+}
+
+func newUTXOCache(budget int) *utxoCache {
+	return &utxoCache{entries: make(map[string]*utxoEntry), budget: budget}
+}
+
+func entrySize(key string, e *utxoEntry) int {
+	return utxoEntryOverhead + len(key) + len(e.out.PubKeyHash)
+}
+
+// needsFlush reports whether the cache should be flushed
+func (c *utxoCache) needsFlush() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.maxLag != 0 && c.best != nil && c.bestHeight-c.stored >= c.maxLag {
+		return true
+	}
+
+	return c.usage > c.budget
+}
+
+// flush writes the dirty entries to the store in one transaction. Clean
+// entries are evicted afterwards while the cache is over its budget.
+func (c *utxoCache) flush(store Store) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.best == nil {
+		return nil
+	}
+
+	err := store.Update(func(tx StoreTx) error {
+		s := tx.UTXO()
+		for key, e := range c.entries {
+			if !e.dirty {
+				continue
+			}
+
+			op, err := decodeOutPointKey([]byte(key))
+			if err != nil {
+				return err
+			}
+			if e.spent {
+				err = s.DeleteOutput(op)
+			} else {
+				err = s.PutOutput(op, e.out)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return s.SetBestBlock(c.best, c.bestHeight)
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, e := range c.entries {
+		if e.spent {
+			c.remove(key, e)
+			continue
+		}
+		e.dirty = false
+		e.fresh = false
+	}
+	c.best = nil
+
+	for key, e := range c.entries {
+		if c.usage <= c.budget {
+			break
+		}
+		c.remove(key, e)
+	}
+
+	return nil
+}
+
+// reset drops every entry, the store's UTXO set was changed directly
+func (c *utxoCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*utxoEntry)
+	c.usage = 0
+	c.best = nil
+}
+
+func (c *utxoCache) remove(key string, e *utxoEntry) {
+	delete(c.entries, key)
+	c.usage -= entrySize(key, e)
+}
+
+func (c *utxoCache) set(key string, e *utxoEntry) {
+	if old, ok := c.entries[key]; ok {
+		c.usage -= entrySize(key, old)
+	}
+	c.entries[key] = e
+	c.usage += entrySize(key, e)
+}
+
+// commit applies the changes of a store transaction that committed
+func (c *utxoCache) commit(t *cacheTx) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, e := range t.loaded {
+		if _, ok := c.entries[key]; !ok {
+			c.set(key, e)
+		}
+	}
+	for key, e := range t.pending {
+		if e == nil {
+			if old, ok := c.entries[key]; ok {
+				c.remove(key, old)
+			}
+			continue
+		}
+		c.set(key, e)
+	}
+	if t.best != nil {
+		if c.best == nil {
+			c.stored = t.stored
+		}
+		c.best, c.bestHeight = t.best, t.bestHeight
+	}
+}
+
+// cacheTx the UTXO set as a store transaction sees it through the cache.
+// Its writes are pending until the transaction commits.
+type cacheTx struct {
+	cache *utxoCache
+	store UTXOStore
+	// pending the entries written by the transaction, nil drops one
+	pending map[string]*utxoEntry
+	// loaded the clean entries read from the store
+	loaded     map[string]*utxoEntry
+	best       []byte
+	bestHeight int
+	// stored the height of the store's best block
+	stored int
+}
+
+func newCacheTx(cache *utxoCache, store UTXOStore) *cacheTx {
+	return &cacheTx{
+		cache:   cache,
+		store:   store,
+		pending: make(map[string]*utxoEntry),
+		loaded:  make(map[string]*utxoEntry),
+	}
+}
+
+// entry returns the entry of the key, reading the store on a miss,
+// nil if the output does not exist
+func (t *cacheTx) entry(key string, op OutPoint) (*utxoEntry, error) {
+	if e, ok := t.pending[key]; ok {
+		return e, nil
+	}
+	if e, ok := t.loaded[key]; ok {
+		return e, nil
+	}
+
+	t.cache.mu.RLock()
+	e, ok := t.cache.entries[key]
+	t.cache.mu.RUnlock()
+	if ok {
+		return e, nil
+	}
+
+	out, err := t.store.Output(op)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	e = &utxoEntry{out: out}
+	t.loaded[key] = e
+	return e, nil
+}
+
+func (t *cacheTx) Output(op OutPoint) (TxOutput, error) {
+	e, err := t.entry(string(outPointKey(op)), op)
+	if err != nil {
+		return TxOutput{}, err
+	}
+	if e == nil || e.spent {
+		return TxOutput{}, fmt.Errorf("output %s: %w", op, ErrNotFound)
+	}
+
+	return e.out, nil
+}
+
+// PutOutput adds an output the store does not hold: either a new one, or
+// one restored by a disconnected block after its spending was written
+func (t *cacheTx) PutOutput(op OutPoint, out TxOutput) error {
+	key := string(outPointKey(op))
+	e, err := t.entry(key, op)
+	if err != nil {
+		return err
+	}
+
+	// a spent entry which is not fresh is still in the store
+	fresh := e == nil || e.fresh
+	t.pending[key] = &utxoEntry{out: out, dirty: true, fresh: fresh}
+	return nil
+}
+
+func (t *cacheTx) DeleteOutput(op OutPoint) error {
+	key := string(outPointKey(op))
+	e, err := t.entry(key, op)
+	if err != nil {
+		return err
+	}
+
+	if e != nil && e.fresh {
+		t.pending[key] = nil
+	} else {
+		t.pending[key] = &utxoEntry{spent: true, dirty: true}
+	}
+	return nil
+}
+
+// ForEach merges the changes of the cache and the transaction
+// into the outputs of the store, in the order of their outpoint
+func (t *cacheTx) ForEach(fn func(op OutPoint, out TxOutput) error) error {
+	changes := make(map[string]*utxoEntry)
+	t.cache.mu.RLock()
+	for key, e := range t.cache.entries {
+		if e.dirty {
+			changes[key] = e
+		}
+	}
+	t.cache.mu.RUnlock()
+	for key, e := range t.pending {
+		if e == nil {
+			e = &utxoEntry{spent: true}
+		}
+		changes[key] = e
+	}
+
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// visits the changes ordered before a stored key
+	next := 0
+	visitChanges := func(before []byte) error {
+		for ; next < len(keys); next++ {
+			key := keys[next]
+			if before != nil && bytes.Compare([]byte(key), before) >= 0 {
+				return nil
+			}
+			e := changes[key]
+			if e.spent {
+				continue
+			}
+			op, err := decodeOutPointKey([]byte(key))
+			if err != nil {
+				return err
+			}
+			err = fn(op, e.out)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := t.store.ForEach(func(op OutPoint, out TxOutput) error {
+		key := outPointKey(op)
+		err := visitChanges(key)
+		if err != nil {
+			return err
+		}
+		if next < len(keys) && keys[next] == string(key) {
+			// the cache has the latest version of the output
+			return nil
+		}
+
+		return fn(op, out)
+	})
+	if err != nil {
+		return err
+	}
+
+	return visitChanges(nil)
+}
+
+func (t *cacheTx) BestBlock() ([]byte, int) {
+	if t.best != nil {
+		return t.best, t.bestHeight
+	}
+
+	t.cache.mu.RLock()
+	best, height := t.cache.best, t.cache.bestHeight
+	t.cache.mu.RUnlock()
+	if best != nil {
+		return best, height
+	}
+
+	return t.store.BestBlock()
+}
+
+func (t *cacheTx) SetBestBlock(hash []byte, height int) error {
+	t.best, t.bestHeight = hash, height
+	_, t.stored = t.store.BestBlock()
+	return nil
+}
+
+func (t *cacheTx) Clear() error {
+	return errors.New("the UTXO cache cannot be cleared, clear the store and reset it")
+}
+
+// cachedStoreTx a store transaction whose UTXO set goes through the cache
+type cachedStoreTx struct {
+	StoreTx
+	utxo *cacheTx
+}
+
+func (t cachedStoreTx) UTXO() UTXOStore {
+	return t.utxo
+}
@@ -0,0 +1,248 @@
+package blockchain
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// storedStats returns the stats of the UTXO set written to the store
+func storedStats(t *testing.T, store Store) *UTXOStats {
+	stats, err := UTxOSet{&Blockchain{store: store}}.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stats
+}
+
+func TestUTXOCache(t *testing.T) {
+	params := privateParams()
+	store := NewMemoryStore()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromAddr, toAddr := string(from.Address(params)), string(to.Address(params))
+
+	bc, err := CreateBlockchain(store, fromAddr, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := UTxOSet{bc}
+	genesis := storedStats(t, store)
+
+	spend, err := NewUTXOTransaction(from, toAddr, 10, &utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cb, err := NewCoinbaseTX(fromAddr, "", 1, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb, spend}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the block is only in the cache, a crash leaves the store behind
+	if got := storedStats(t, store); !bytes.Equal(got.Hash, genesis.Hash) {
+		t.Errorf("store changed before a flush: %+v", got)
+	}
+	if _, err := NewBlockchain(store, params); !errors.Is(err, ErrUTXOMismatch) {
+		t.Fatalf("open with an unflushed cache: %v", err)
+	}
+	want, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want.BestBlock, bc.tip) || want.Height != 1 {
+		t.Errorf("cached UTXO set at %x height %d", want.BestBlock, want.Height)
+	}
+
+	// a failed block leaves the cache as it was
+	usage := bc.utxo.usage
+	if _, err := bc.AddBlock([]*Transaction{cb, spend}); !errors.Is(err, ErrMissingInput) {
+		t.Fatalf("double spend: %v", err)
+	}
+	if bc.utxo.usage != usage {
+		t.Errorf("cache usage %d after a failed block, want %d", bc.utxo.usage, usage)
+	}
+
+	if err := bc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := storedStats(t, store); !bytes.Equal(got.Hash, want.Hash) || !bytes.Equal(got.BestBlock, want.BestBlock) {
+		t.Errorf("flushed UTXO set = %+v, want %+v", got, want)
+	}
+	if _, err := NewBlockchain(store, params); err != nil {
+		t.Fatal(err)
+	}
+
+	// over its budget the cache is flushed at the next block and emptied
+	bc.SetUTXOCacheSize(0)
+	cb, err = NewCoinbaseTX(fromAddr, "", 2, 0, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+		t.Fatal(err)
+	}
+	if len(bc.utxo.entries) != 0 || bc.utxo.usage != 0 {
+		t.Errorf("cache holds %d entries, %d bytes over a budget of 0", len(bc.utxo.entries), bc.utxo.usage)
+	}
+	if got := storedStats(t, store); !bytes.Equal(got.BestBlock, bc.tip) {
+		t.Errorf("store at %x, want the tip %x", got.BestBlock, bc.tip)
+	}
+}
+
+func TestUTXOCacheReorg(t *testing.T) {
+	params := privateParams()
+	store := NewMemoryStore()
+
+	from, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := wallet.NewWallet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromAddr, toAddr := string(from.Address(params)), string(to.Address(params))
+
+	bc, err := CreateBlockchain(store, fromAddr, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := UTxOSet{bc}
+
+	// the first block pays the recipient, the second spends it back, so the
+	// second block spends an output the store has never held
+	mine := func(w *wallet.Wallet, to string, height int) {
+		spend, err := NewUTXOTransaction(w, to, 10, &utxo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cb, err := NewCoinbaseTX(fromAddr, "", height, 0, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb, spend}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mine(from, toAddr, 1)
+	if err := bc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := storedStats(t, store)
+	mine(to, fromAddr, 2)
+
+	if _, err := bc.DisconnectTip(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Hash, want.Hash) || !bytes.Equal(got.BestBlock, want.BestBlock) {
+		t.Errorf("cached UTXO set after disconnect = %+v, want %+v", got, want)
+	}
+
+	// disconnecting a flushed block restores outputs the store deleted
+	if _, err := bc.DisconnectTip(); err != nil {
+		t.Fatal(err)
+	}
+	want, err = utxo.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	got = storedStats(t, store)
+	if !bytes.Equal(got.Hash, want.Hash) || !bytes.Equal(got.BestBlock, want.BestBlock) {
+		t.Errorf("flushed UTXO set after disconnect = %+v, want %+v", got, want)
+	}
+	if want.Height != 0 {
+		t.Errorf("UTXO set at height %d after disconnecting to genesis", want.Height)
+	}
+}
+
+func BenchmarkConnectBlock(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		benchmarkConnectBlock(b, true)
+	})
+	b.Run("uncached", func(b *testing.B) {
+		benchmarkConnectBlock(b, false)
+	})
+}
+
+// benchmarkConnectBlock connects and disconnects a block spending the
+// coinbase outputs of 200 blocks
+func benchmarkConnectBlock(b *testing.B, cached bool) {
+	params := privateParams()
+	dir := b.TempDir()
+	store, err := OpenFlatFileStore(filepath.Join(dir, "block-chain.db"), filepath.Join(dir, "blocks"), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	w, err := wallet.NewWallet()
+	if err != nil {
+		b.Fatal(err)
+	}
+	address := string(w.Address(params))
+
+	bc, err := CreateBlockchain(store, address, params)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer bc.Close()
+	if !cached {
+		bc.utxo = nil
+	}
+
+	// inputs are not signed, connecting a block does not verify them
+	spend := &Transaction{}
+	for h := 1; h <= 200; h++ {
+		cb, err := NewCoinbaseTX(address, "", h, 0, params)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := bc.AddBlock([]*Transaction{cb}); err != nil {
+			b.Fatal(err)
+		}
+		spend.Vin = append(spend.Vin, TxInput{cb.ID, 0, nil, w.PublicKey})
+		spend.Vout = append(spend.Vout, cb.Vout[0])
+	}
+	spend.ID = spend.Hash()
+
+	cb, err := NewCoinbaseTX(address, "", 201, 0, params)
+	if err != nil {
+		b.Fatal(err)
+	}
+	block := NewBlock([]*Transaction{cb, spend}, bc.tip, 201, params.TargetBits)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := bc.connect(block)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.StopTimer()
+		_, err = bc.DisconnectTip()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+	}
+}
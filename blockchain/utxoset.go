@@ -0,0 +1,393 @@
+package blockchain
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// UTxOSet ...
+type UTxOSet struct {
+	BC *Blockchain
+}
+
+// reindexBatchSize the default number of blocks applied per store
+// transaction by Reindex
+const reindexBatchSize = 500
+
+// ReindexOptions tunes Reindex
+type ReindexOptions struct {
+	// Full rebuilds from the genesis block, even if the UTXO set only
+	// lags behind the tip
+	Full bool
+	// BatchSize the number of blocks applied per store transaction
+	BatchSize int
+	// Progress is called after each batch with the height reached and the
+	// height of the tip, an error stops the reindex
+	Progress func(height, tip int) error
+}
+
+// Reindex brings the UTXO set and the transaction index up to date with
+// the chain. The blocks are applied forward in batches, each batch commits
+// with the UTXO set's best block, which is where an interrupted reindex
+// resumes. A UTXO set that is not on the chain is rebuilt from the genesis
+// block.
+func (u UTxOSet) Reindex(opts ReindexOptions) error {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = reindexBatchSize
+	}
+
+	// the blocks are applied to the store directly
+	err := u.BC.Flush()
+	if err != nil {
+		return err
+	}
+	if u.BC.utxo != nil {
+		defer u.BC.utxo.reset()
+	}
+
+	var best []byte
+	if !opts.Full {
+		err := u.BC.store.View(func(tx StoreTx) error {
+			best, _ = tx.UTXO().BestBlock()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// the blocks after the best block, walking the headers back from the tip
+	var hashes [][]byte
+	found := false
+	iter := u.BC.Iterator()
+	for len(iter.CurrentHash()) != 0 {
+		if best != nil && bytes.Equal(iter.CurrentHash(), best) {
+			found = true
+			break
+		}
+		hashes = append(hashes, iter.CurrentHash())
+		_, err := iter.NextHeader()
+		if err != nil {
+			return err
+		}
+	}
+	tip, err := u.BC.GetBestHeight()
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		err := u.BC.store.Update(func(tx StoreTx) error {
+			// blocks are pruned oldest first, keep the set if they are gone
+			_, err := tx.Blocks().Block(hashes[len(hashes)-1])
+			if err != nil {
+				return err
+			}
+
+			err = tx.UTXO().Clear()
+			if err != nil {
+				return err
+			}
+			return tx.TxIndex().Clear()
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for len(hashes) != 0 {
+		n := opts.BatchSize
+		if n > len(hashes) {
+			n = len(hashes)
+		}
+		batch := hashes[len(hashes)-n:]
+		hashes = hashes[:len(hashes)-n]
+
+		var height int
+		err := u.BC.store.Update(func(tx StoreTx) error {
+			for i := len(batch) - 1; i >= 0; i-- {
+				block, err := tx.Blocks().Block(batch[i])
+				if err != nil {
+					return err
+				}
+				err = applyBlock(tx, block)
+				if err != nil {
+					return err
+				}
+				height = block.Height
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		if opts.Progress != nil {
+			err = opts.Progress(height, tip)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyBlock indexes the transactions of a block, spends the outputs its
+// inputs refer to and adds its outputs to the UTXO set. The spent outputs
+// are kept as the undo data of the block.
+func applyBlock(tx StoreTx, block *Block) error {
+	s := tx.UTXO()
+	var spent []SpentOutput
+
+	for _, t := range block.Transactions {
+		if !t.IsCoinbase() {
+			for _, in := range t.Vin {
+				op := in.OutPoint()
+				out, err := s.Output(op)
+				if errors.Is(err, ErrNotFound) {
+					return fmt.Errorf("transaction %x spends %s: %w", t.ID, op, ErrMissingInput)
+				}
+				if err != nil {
+					return err
+				}
+				spent = append(spent, SpentOutput{op, out})
+				err = s.DeleteOutput(op)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for index, out := range t.Vout {
+			err := s.PutOutput(OutPoint{t.ID, index}, out)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.TxIndex().Put(t.ID, block.Hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tx.Blocks().PutUndo(block.Hash, spent)
+	if err != nil {
+		return err
+	}
+
+	return s.SetBestBlock(block.Hash, block.Height)
+}
+
+// FindUTXO ...
+func (u UTxOSet) FindUTXO(address []byte) ([]TxOutput, error) {
+	var utxos []TxOutput
+
+	err := u.BC.view(func(tx StoreTx) error {
+		return tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+			if out.CanUnlockedWith(address) {
+				utxos = append(utxos, out)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return utxos, nil
+}
+
+// UnspentOutput an output of the UTXO set and its outpoint
+type UnspentOutput struct {
+	OutPoint OutPoint
+	Output   TxOutput
+}
+
+// ListUnspent returns the unspent outputs locked to the public key hash, in
+// the order of their outpoint
+func (u UTxOSet) ListUnspent(pubKeyHash []byte) ([]UnspentOutput, error) {
+	var unspent []UnspentOutput
+
+	err := u.BC.view(func(tx StoreTx) error {
+		return tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+			if out.CanUnlockedWith(pubKeyHash) {
+				unspent = append(unspent, UnspentOutput{op, out})
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unspent, nil
+}
+
+// Output returns the unspent output of the outpoint, ErrNotFound when it
+// is spent or does not exist
+func (u UTxOSet) Output(op OutPoint) (TxOutput, error) {
+	var out TxOutput
+
+	err := u.BC.view(func(tx StoreTx) error {
+		var err error
+		out, err = tx.UTXO().Output(op)
+		return err
+	})
+
+	return out, err
+}
+
+// selectOutputs returns the outputs of the key hash a transaction paying
+// target spends: the inputs of the coin control, or else the outputs not
+// locked its selector selects
+func (u UTxOSet) selectOutputs(keyType wallet.KeyType, pubKeyHash []byte, target int, cc *CoinControl) ([]UnspentOutput, error) {
+	if len(cc.Inputs) != 0 {
+		var selected []UnspentOutput
+		err := u.BC.view(func(tx StoreTx) error {
+			for _, op := range cc.Inputs {
+				out, err := tx.UTXO().Output(op)
+				if errors.Is(err, ErrNotFound) {
+					return fmt.Errorf("output %s: %w", op, ErrMissingInput)
+				}
+				if err != nil {
+					return err
+				}
+				if out.KeyType != keyType || !out.CanUnlockedWith(pubKeyHash) {
+					return fmt.Errorf("output %s is not the sender's", op)
+				}
+				if cc.Locked[op.String()] {
+					return fmt.Errorf("output %s is locked", op)
+				}
+				selected = append(selected, UnspentOutput{op, out})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return selected, nil
+	}
+
+	unspent, err := u.ListUnspent(pubKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []UnspentOutput
+	for _, uo := range unspent {
+		if uo.Output.KeyType == keyType && !cc.Locked[uo.OutPoint.String()] {
+			candidates = append(candidates, uo)
+		}
+	}
+
+	selector := cc.Selector
+	if selector == nil {
+		selector = DefaultCoinSelector
+	}
+
+	return selector.Select(candidates, target, cc.Fees)
+}
+
+// TotalAmount returns the sum of all unspent outputs
+func (u UTxOSet) TotalAmount() (int, error) {
+	total := 0
+
+	err := u.BC.view(func(tx StoreTx) error {
+		return tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+			total += out.Value
+			return nil
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+// UTXOStats summarizes the UTXO set
+type UTXOStats struct {
+	BestBlock    []byte
+	Height       int
+	Transactions int
+	Outputs      int
+	TotalAmount  int
+	// Hash commits to every unspent output, nodes holding the same
+	// set get the same hash whatever their store
+	Hash []byte
+}
+
+// Stats walks the UTXO set and hashes it
+func (u UTxOSet) Stats() (*UTXOStats, error) {
+	var stats *UTXOStats
+
+	err := u.BC.view(func(tx StoreTx) error {
+		h := newUTXOHasher()
+		err := tx.UTXO().ForEach(func(op OutPoint, out TxOutput) error {
+			h.add(op, out)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		stats = h.stats()
+		stats.BestBlock, stats.Height = tx.UTXO().BestBlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// utxoHasher counts and hashes the outputs of a UTXO set, they must be
+// added in the order of their outpoint. Each one is hashed as the txid,
+// the 4-byte index, the 8-byte value and the public key hash prefixed by
+// its script header.
+type utxoHasher struct {
+	h        hash.Hash
+	lastTxid []byte
+	counts   UTXOStats
+}
+
+func newUTXOHasher() *utxoHasher {
+	return &utxoHasher{h: sha256.New()}
+}
+
+func (u *utxoHasher) add(op OutPoint, out TxOutput) {
+	if !bytes.Equal(op.Txid, u.lastTxid) {
+		u.counts.Transactions++
+		u.lastTxid = op.Txid
+	}
+	u.counts.Outputs++
+	u.counts.TotalAmount += out.Value
+
+	var buf [8]byte
+	u.h.Write(op.Txid)
+	binary.BigEndian.PutUint32(buf[:4], uint32(op.Vout))
+	u.h.Write(buf[:4])
+	binary.BigEndian.PutUint64(buf[:], uint64(out.Value))
+	u.h.Write(buf[:])
+	binary.BigEndian.PutUint32(buf[:4], out.scriptHeader())
+	u.h.Write(buf[:4])
+	u.h.Write(out.PubKeyHash)
+}
+
+func (u *utxoHasher) stats() *UTXOStats {
+	stats := u.counts
+	stats.Hash = u.h.Sum(nil)
+
+	return &stats
+}
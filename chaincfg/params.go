@@ -0,0 +1,213 @@
+// Package chaincfg defines the parameters of the networks a chain can run on
+package chaincfg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Params defines a network: its genesis block, address format,
+// default ports, difficulty, emission and where its data is stored
+type Params struct {
+	Name string
+
+	// GenesisCoinbaseData and GenesisTimestamp make the genesis block
+	// of every network distinct
+	GenesisCoinbaseData string
+	GenesisTimestamp    int64
+	// GenesisPubKeyHash the hex encoded public key hash the coinbase of
+	// the genesis block pays, nobody holds its key
+	GenesisPubKeyHash string
+	// GenesisNonce and GenesisHash the proof of work of the genesis block,
+	// a chain starting from another block is not on the network. A
+	// private network has no GenesisHash, its genesis block pays the
+	// address of its creator.
+	GenesisNonce int
+	GenesisHash  string
+
+	// PubKeyHashAddrID version byte of pay-to-pubkey-hash addresses
+	// of P-256 keys
+	PubKeyHashAddrID byte
+	// Secp256k1PubKeyHashAddrID and SchnorrPubKeyHashAddrID version bytes
+	// of the addresses of secp256k1 ECDSA and Schnorr keys
+	Secp256k1PubKeyHashAddrID byte
+	SchnorrPubKeyHashAddrID   byte
+	// ScriptHashAddrID version byte of pay-to-script-hash addresses
+	ScriptHashAddrID byte
+	// Bech32HRP the human-readable part of Bech32 addresses
+	Bech32HRP string
+
+	DefaultPort string
+	RPCPort     string
+
+	TargetBits int
+	Emission   EmissionSchedule
+
+	// GenerateSupported allows mining blocks on demand with generate
+	GenerateSupported bool
+
+	// DataDir directory of the chain and wallet files,
+	// relative to the working directory
+	DataDir string
+
+	// AssumeUTXO the UTXO set snapshots a node may start from, the
+	// snapshots of a private chain are pinned with WithAssumeUTXO. A
+	// snapshot is pinned once its hash is checked against a node which
+	// validated every block up to it.
+	AssumeUTXO []AssumeUTXO
+}
+
+// AssumeUTXO pins a UTXO set snapshot: the block it was taken at and
+// the hash of the set gettxoutsetinfo reports, both hex encoded
+type AssumeUTXO struct {
+	Height    int
+	BlockHash string
+	SetHash   string
+}
+
+// MainNetParams the main network
+var MainNetParams = Params{
+	Name:                      "mainnet",
+	GenesisCoinbaseData:       "Genesis data",
+	GenesisTimestamp:          1525104000,
+	GenesisPubKeyHash:         "fe479063399eccb0a6d40cbe385d5d9ed6285356",
+	GenesisNonce:              57324788,
+	GenesisHash:               "0000008939647dcfb8561d7c7caad4233940293380ecd578db09b34f88d9ed90",
+	PubKeyHashAddrID:          0x00,
+	Secp256k1PubKeyHashAddrID: 0x3f,
+	SchnorrPubKeyHashAddrID:   0x41,
+	ScriptHashAddrID:          0x05,
+	Bech32HRP:                 "bcg",
+	DefaultPort:               "8333",
+	RPCPort:                   "8332",
+	TargetBits:                24,
+	Emission:                  EmissionSchedule{50, 210000, 21000000},
+	DataDir:                   "",
+	AssumeUTXO: []AssumeUTXO{
+		{0, "0000008939647dcfb8561d7c7caad4233940293380ecd578db09b34f88d9ed90", "37838452c7a96f7295f11a7c06e7a4c42bc5164257c0ac043e8bc668e85932e8"},
+	},
+}
+
+// TestNetParams the public test network, cheaper to mine than mainnet
+var TestNetParams = Params{
+	Name:                      "testnet",
+	Bech32HRP:                 "tbcg",
+	GenesisCoinbaseData:       "Testnet genesis data",
+	GenesisTimestamp:          1525190400,
+	GenesisPubKeyHash:         "fce23a0aac9204a338691cead898f052ce585518",
+	GenesisNonce:              54894,
+	GenesisHash:               "0000f76a2f0e9f9d05ebf5cfda4b51ddf86f47ff010cd43af2a3cb37d06cd6ae",
+	PubKeyHashAddrID:          0x6f,
+	Secp256k1PubKeyHashAddrID: 0x7d,
+	SchnorrPubKeyHashAddrID:   0x7f,
+	ScriptHashAddrID:          0xc4,
+	DefaultPort:               "18333",
+	RPCPort:                   "18332",
+	TargetBits:                16,
+	Emission:                  EmissionSchedule{50, 210000, 21000000},
+	DataDir:                   "testnet",
+	AssumeUTXO: []AssumeUTXO{
+		{0, "0000f76a2f0e9f9d05ebf5cfda4b51ddf86f47ff010cd43af2a3cb37d06cd6ae", "22ea698defcecb76a4c25d039eb875b33d034a48f116e4b6cfe2f92fd74ad579"},
+	},
+}
+
+// RegTestParams the regression test network, blocks are found almost
+// instantly so it is suitable for integration tests. It shares the
+// version bytes of testnet, as in Bitcoin, so the tools and wallets made
+// for testnet take its Base58Check addresses; the Bech32 prefix tells
+// them apart.
+var RegTestParams = Params{
+	Name:                      "regtest",
+	Bech32HRP:                 "bcgrt",
+	GenesisCoinbaseData:       "Regtest genesis data",
+	GenesisTimestamp:          1525276800,
+	GenesisPubKeyHash:         "fb95be64eb45098e88513864719495194ceba718",
+	GenesisNonce:              0,
+	GenesisHash:               "3aa5b1403c6c21179f44c048baee1389410a56fa207dba09d01f127489ea0700",
+	PubKeyHashAddrID:          0x6f,
+	Secp256k1PubKeyHashAddrID: 0x7d,
+	SchnorrPubKeyHashAddrID:   0x7f,
+	ScriptHashAddrID:          0xc4,
+	DefaultPort:               "18444",
+	RPCPort:                   "18443",
+	TargetBits:                1,
+	Emission:                  EmissionSchedule{50, 150, 21000000},
+	GenerateSupported:         true,
+	DataDir:                   "regtest",
+	AssumeUTXO: []AssumeUTXO{
+		{0, "3aa5b1403c6c21179f44c048baee1389410a56fa207dba09d01f127489ea0700", "8731c1ea5844e964d09d3d1aa206371f5dad0af02f2c0a505e020105ba939722"},
+	},
+}
+
+// Networks the presets of the known networks
+var Networks = []*Params{&MainNetParams, &TestNetParams, &RegTestParams}
+
+// NetParams finds the preset of a network by its name
+func NetParams(name string) (*Params, error) {
+	for _, params := range Networks {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown network: %s", name)
+}
+
+// AssumedUTXO returns the pinned snapshot taken at the block, if any
+func (p *Params) AssumedUTXO(blockHash string) (AssumeUTXO, bool) {
+	for _, a := range p.AssumeUTXO {
+		if a.BlockHash == blockHash {
+			return a, true
+		}
+	}
+
+	return AssumeUTXO{}, false
+}
+
+// WithAssumeUTXO returns a copy of the parameters which also pins the
+// snapshot
+func (p *Params) WithAssumeUTXO(a AssumeUTXO) *Params {
+	params := *p
+	params.AssumeUTXO = append(append([]AssumeUTXO{}, p.AssumeUTXO...), a)
+
+	return &params
+}
+
+// ParseAssumeUTXO parses a snapshot pinned as height:blockhash:sethash,
+// the hashes hex encoded as dumptxoutset prints them
+func ParseAssumeUTXO(s string) (AssumeUTXO, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return AssumeUTXO{}, fmt.Errorf("assumeutxo %q is not height:blockhash:sethash", s)
+	}
+	height, err := strconv.Atoi(parts[0])
+	if err != nil || height < 0 {
+		return AssumeUTXO{}, fmt.Errorf("assumeutxo height %q", parts[0])
+	}
+	for _, h := range parts[1:] {
+		d, err := hex.DecodeString(h)
+		if err != nil || len(d) != sha256.Size {
+			return AssumeUTXO{}, fmt.Errorf("assumeutxo hash %q", h)
+		}
+	}
+
+	return AssumeUTXO{height, strings.ToLower(parts[1]), strings.ToLower(parts[2])}, nil
+}
+
+// DataFile returns the path of a file in the data directory of the network,
+// creating the directory if needed
+func (p *Params) DataFile(name string) (string, error) {
+	if len(p.DataDir) != 0 {
+		err := os.MkdirAll(p.DataDir, 0700)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return filepath.Join(p.DataDir, name), nil
+}
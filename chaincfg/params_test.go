@@ -0,0 +1,35 @@
+package chaincfg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssumeUTXO(t *testing.T) {
+	block, set := strings.Repeat("ab", 32), strings.Repeat("CD", 32)
+
+	a, err := ParseAssumeUTXO("7:" + block + ":" + set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Height != 7 || a.BlockHash != block || a.SetHash != strings.ToLower(set) {
+		t.Errorf("parsed %+v", a)
+	}
+
+	for _, s := range []string{
+		"", "7:" + block, "x:" + block + ":" + set, "-1:" + block + ":" + set,
+		"7:" + block[2:] + ":" + set, "7:" + block + ":zz", "7:" + block + ":" + set + ":1",
+	} {
+		if _, err := ParseAssumeUTXO(s); err == nil {
+			t.Errorf("parsed %q", s)
+		}
+	}
+
+	params := RegTestParams.WithAssumeUTXO(a)
+	if got, ok := params.AssumedUTXO(block); !ok || got != a {
+		t.Errorf("pinned snapshot %+v, %v", got, ok)
+	}
+	if _, ok := RegTestParams.AssumedUTXO(block); ok {
+		t.Error("the regtest preset was changed")
+	}
+}
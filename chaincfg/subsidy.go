@@ -0,0 +1,66 @@
+package chaincfg
+
+// EmissionSchedule describes how new coins are issued by coinbase transactions
+type EmissionSchedule struct {
+	// InitialSubsidy reward of a block before the first halving
+	InitialSubsidy int
+	// HalvingInterval number of blocks after which the reward is halved,
+	// zero or negative disables halving
+	HalvingInterval int
+	// MaxSupply hard cap of coins that can ever be issued
+	MaxSupply int
+}
+
+// Subsidy returns the reward of the block at the given height
+func (s EmissionSchedule) Subsidy(height int) int {
+	return s.Supply(height) - s.Supply(height-1)
+}
+
+// Supply returns the amount issued by all blocks from genesis
+// up to and including the given height
+func (s EmissionSchedule) Supply(height int) int {
+	if height < 0 {
+		return 0
+	}
+
+	if s.HalvingInterval <= 0 {
+		return s.capped(s.InitialSubsidy * (height + 1))
+	}
+
+	total := 0
+	for start := 0; start <= height; start += s.HalvingInterval {
+		reward := s.scheduled(start)
+		if reward == 0 {
+			break
+		}
+
+		end := start + s.HalvingInterval - 1
+		if end > height {
+			end = height
+		}
+		total += reward * (end - start + 1)
+		if total >= s.MaxSupply {
+			break
+		}
+	}
+
+	return s.capped(total)
+}
+
+// scheduled returns the halved reward at height, ignoring the supply cap
+func (s EmissionSchedule) scheduled(height int) int {
+	halvings := height / s.HalvingInterval
+	if halvings >= 63 {
+		return 0
+	}
+
+	return s.InitialSubsidy >> uint(halvings)
+}
+
+func (s EmissionSchedule) capped(amount int) int {
+	if amount > s.MaxSupply {
+		return s.MaxSupply
+	}
+
+	return amount
+}
@@ -0,0 +1,35 @@
+package chaincfg
+
+import "testing"
+
+func TestEmissionSchedule(t *testing.T) {
+	s := EmissionSchedule{InitialSubsidy: 50, HalvingInterval: 10, MaxSupply: 1000}
+
+	cases := []struct {
+		height  int
+		subsidy int
+	}{
+		{0, 50}, {9, 50}, {10, 25}, {19, 25}, {20, 12}, {30, 6}, {60, 0},
+	}
+	for _, c := range cases {
+		if got := s.Subsidy(c.height); got != c.subsidy {
+			t.Errorf("Subsidy(%d) = %d, want %d", c.height, got, c.subsidy)
+		}
+	}
+
+	issued := 0
+	for height := 0; height < 100; height++ {
+		issued += s.Subsidy(height)
+		if issued != s.Supply(height) {
+			t.Fatalf("Supply(%d) = %d, want %d", height, s.Supply(height), issued)
+		}
+	}
+
+	capped := EmissionSchedule{InitialSubsidy: 50, HalvingInterval: 10, MaxSupply: 520}
+	if got := capped.Supply(1000); got != 520 {
+		t.Errorf("capped Supply = %d, want 520", got)
+	}
+	if got := capped.Subsidy(10); got != 20 {
+		t.Errorf("capped Subsidy(10) = %d, want 20", got)
+	}
+}
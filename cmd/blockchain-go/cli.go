@@ -0,0 +1,852 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/MikasaAkerman/blockchain-go/blockchain"
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+const (
+	cmdPrintChain     = "printchain"
+	cmdGetBalance     = "getbalance"
+	cmdSend           = "send"
+	cmdCreateWallet   = "createwallet"
+	cmdCreateMultiSig = "createmultisig"
+	cmdListAddresses  = "listaddresses"
+	cmdGetSupply      = "getsupply"
+	cmdGenerate       = "generate"
+	cmdGetTxOutInfo   = "gettxoutsetinfo"
+	cmdDumpTxOutSet   = "dumptxoutset"
+	cmdLoadTxOutSet   = "loadtxoutset"
+	cmdReindex        = "reindex"
+	cmdCreatePSBT     = "createpsbt"
+	cmdSignPSBT       = "signpsbt"
+	cmdCombinePSBT    = "combinepsbt"
+	cmdFinalizePSBT   = "finalizepsbt"
+	cmdSendPSBT       = "sendpsbt"
+	cmdCreateRawTx    = "createrawtransaction"
+	cmdDecodeRawTx    = "decoderawtransaction"
+	cmdSignRawTx      = "signrawtransaction"
+	cmdSendRawTx      = "sendrawtransaction"
+	cmdListUnspent    = "listunspent"
+	cmdLockUnspent    = "lockunspent"
+	cmdListLocked     = "listlockunspent"
+	cmdDaemon         = "daemon"
+)
+
+// CLI the command-line interface of blockchain
+type CLI struct {
+	out    io.Writer
+	params *chaincfg.Params
+	// pruneMB the size of the block files to stay under, 0 keeps every block
+	pruneMB uint64
+	// dbCacheMB the memory budget of the UTXO cache
+	dbCacheMB int
+	// node is set when the commands are executed by a daemon,
+	// which keeps the chain and wallets open
+	node *node
+	// globals the global flags of the command line
+	globals *flag.FlagSet
+}
+
+// NewCLI ...
+func NewCLI() *CLI {
+	return &CLI{out: os.Stdout, params: &chaincfg.MainNetParams}
+}
+
+// Run start cli
+func (cli *CLI) Run() {
+	globalFlags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	network := globalFlags.String("network", chaincfg.MainNetParams.Name, "The network to work on: mainnet, testnet or regtest")
+	globalFlags.Uint64Var(&cli.pruneMB, "prune", 0, "Remove old blocks to keep the block files under this many MB, 0 keeps every block")
+	globalFlags.IntVar(&cli.dbCacheMB, "dbcache", blockchain.DefaultUTXOCacheSize>>20, "Keep up to this many MB of the UTXO set in memory before writing it to the database")
+	assumeUTXO := globalFlags.String("assumeutxo", "", "Pin the UTXO set snapshot height:blockhash:sethash, for testing on regtest only")
+	err := globalFlags.Parse(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+	cli.globals = globalFlags
+
+	cli.params, err = chaincfg.NetParams(*network)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(*assumeUTXO) != 0 {
+		// whoever hands out a snapshot could hand out its hash too
+		if cli.params != &chaincfg.RegTestParams {
+			log.Fatalf("%s snapshots are only pinned in its parameters", cli.params.Name)
+		}
+		a, err := chaincfg.ParseAssumeUTXO(*assumeUTXO)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cli.params = cli.params.WithAssumeUTXO(a)
+	}
+
+	args := globalFlags.Args()
+	if len(args) == 0 {
+		log.Printf("usage: %s [-network name] <command> [args]", os.Args[0])
+		os.Exit(1)
+	}
+
+	if args[0] == cmdDaemon {
+		err = cli.runDaemon(args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// forward the command to a running daemon, which holds the database lock
+	forwarded, err := cli.callDaemon(args)
+	if forwarded {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = cli.execute(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// execute runs a single command with its arguments
+func (cli *CLI) execute(args []string) error {
+	printChainCmd := flag.NewFlagSet(cmdPrintChain, flag.ContinueOnError)
+	getBalanceCmd := flag.NewFlagSet(cmdGetBalance, flag.ContinueOnError)
+	sendCmd := flag.NewFlagSet(cmdSend, flag.ContinueOnError)
+	createWalletCmd := flag.NewFlagSet(cmdCreateWallet, flag.ContinueOnError)
+	createMultiSigCmd := flag.NewFlagSet(cmdCreateMultiSig, flag.ContinueOnError)
+	listAddressesCmd := flag.NewFlagSet(cmdListAddresses, flag.ContinueOnError)
+	getSupplyCmd := flag.NewFlagSet(cmdGetSupply, flag.ContinueOnError)
+	generateCmd := flag.NewFlagSet(cmdGenerate, flag.ContinueOnError)
+	getTxOutInfoCmd := flag.NewFlagSet(cmdGetTxOutInfo, flag.ContinueOnError)
+	dumpTxOutSetCmd := flag.NewFlagSet(cmdDumpTxOutSet, flag.ContinueOnError)
+	loadTxOutSetCmd := flag.NewFlagSet(cmdLoadTxOutSet, flag.ContinueOnError)
+	reindexCmd := flag.NewFlagSet(cmdReindex, flag.ContinueOnError)
+	createPSBTCmd := flag.NewFlagSet(cmdCreatePSBT, flag.ContinueOnError)
+	signPSBTCmd := flag.NewFlagSet(cmdSignPSBT, flag.ContinueOnError)
+	combinePSBTCmd := flag.NewFlagSet(cmdCombinePSBT, flag.ContinueOnError)
+	finalizePSBTCmd := flag.NewFlagSet(cmdFinalizePSBT, flag.ContinueOnError)
+	sendPSBTCmd := flag.NewFlagSet(cmdSendPSBT, flag.ContinueOnError)
+	createRawTxCmd := flag.NewFlagSet(cmdCreateRawTx, flag.ContinueOnError)
+	decodeRawTxCmd := flag.NewFlagSet(cmdDecodeRawTx, flag.ContinueOnError)
+	signRawTxCmd := flag.NewFlagSet(cmdSignRawTx, flag.ContinueOnError)
+	sendRawTxCmd := flag.NewFlagSet(cmdSendRawTx, flag.ContinueOnError)
+	listUnspentCmd := flag.NewFlagSet(cmdListUnspent, flag.ContinueOnError)
+	lockUnspentCmd := flag.NewFlagSet(cmdLockUnspent, flag.ContinueOnError)
+	listLockedCmd := flag.NewFlagSet(cmdListLocked, flag.ContinueOnError)
+
+	getBalanceAddress := getBalanceCmd.String("address", "", "The address to get balance for")
+	sendFrom := sendCmd.String("from", "", "The origin address of BTC")
+	sendTo := sendCmd.String("to", "", "The remote address of BTC")
+	sendAmount := sendCmd.Int("amount", 0, "The amount of BTC")
+	sendOutputs := sendCmd.String("outputs", "", "The comma-separated recipients, as address:amount, instead of to and amount")
+	sendInputs := sendCmd.String("inputs", "", "The comma-separated outpoints to spend, as txid:vout, instead of selecting them")
+	sendChange := sendCmd.String("change", "", "The address the change goes to, the origin address by default")
+	sendSelection := sendCmd.String("selection", "bnb", "The coin selection: bnb, largest, smallest or random")
+	sendFeeRate := sendCmd.Int("feerate", 0, "The fee paid per 1000 bytes of the transaction")
+	sendLongTermFeeRate := sendCmd.Int("longtermfeerate", 0, "The fee per 1000 bytes the change is expected to be spent at, which selection weighs the inputs against")
+	generateNum := generateCmd.Int("n", 1, "The number of blocks to generate")
+	generateAddress := generateCmd.String("address", "", "The address receiving the block rewards")
+	dumpFile := dumpTxOutSetCmd.String("file", "", "The file to write the UTXO set snapshot to")
+	loadFile := loadTxOutSetCmd.String("file", "", "The UTXO set snapshot to start the chain from")
+	createWalletKey := createWalletCmd.String("key", wallet.KeyP256.String(), "The key type of the wallet: p256, secp256k1 or schnorr")
+	createWalletType := createWalletCmd.String("type", wallet.AddressBase58.String(), "The address format of the wallet: base58 or bech32")
+	multiSigRequired := createMultiSigCmd.Int("required", 1, "The number of signatures spending requires")
+	multiSigAddresses := createMultiSigCmd.String("addresses", "", "The comma-separated addresses of the wallets whose keys may sign")
+	multiSigType := createMultiSigCmd.String("type", wallet.AddressBase58.String(), "The address format of the script: base58 or bech32")
+	createPSBTFrom := createPSBTCmd.String("from", "", "The address whose outputs are spent")
+	createPSBTTo := createPSBTCmd.String("to", "", "The address to pay")
+	createPSBTAmount := createPSBTCmd.Int("amount", 0, "The amount to pay")
+	createPSBTSigHash := createPSBTCmd.String("sighash", blockchain.SigHashAll.String(), "The signature hash type of the inputs: ALL, NONE or SINGLE, optionally with |ANYONECANPAY")
+	signPSBT := signPSBTCmd.String("psbt", "", "The base64 partially signed transaction")
+	combinePSBTs := combinePSBTCmd.String("psbt", "", "The comma-separated base64 partially signed transactions")
+	finalizePSBT := finalizePSBTCmd.String("psbt", "", "The base64 partially signed transaction")
+	sendPSBT := sendPSBTCmd.String("psbt", "", "The base64 partially signed transaction")
+	sendPSBTAddress := sendPSBTCmd.String("address", "", "The address receiving the block reward")
+	createRawTxInputs := createRawTxCmd.String("inputs", "", "The comma-separated outpoints to spend, as txid:vout")
+	createRawTxOutputs := createRawTxCmd.String("outputs", "", "The comma-separated outputs to pay, as address:amount")
+	decodeRawTx := decodeRawTxCmd.String("hex", "", "The hex raw transaction")
+	signRawTx := signRawTxCmd.String("hex", "", "The hex raw transaction")
+	signRawTxSigHash := signRawTxCmd.String("sighash", blockchain.SigHashAll.String(), "The signature hash type of the inputs: ALL, NONE or SINGLE, optionally with |ANYONECANPAY")
+	sendRawTx := sendRawTxCmd.String("hex", "", "The hex raw transaction")
+	sendRawTxAddress := sendRawTxCmd.String("address", "", "The address receiving the block reward")
+	listUnspentAddress := listUnspentCmd.String("address", "", "The address to list the outputs of, every wallet address by default")
+	lockUnspentOutputs := lockUnspentCmd.String("outputs", "", "The comma-separated outpoints to lock, as txid:vout")
+	lockUnspentUnlock := lockUnspentCmd.Bool("unlock", false, "Unlock the outputs instead")
+	reindexFull := reindexCmd.Bool("full", false, "Rebuild the UTXO set from the genesis block")
+
+	var err error
+	switch args[0] {
+	case cmdPrintChain:
+		err = parseFlags(printChainCmd, args[1:], cli.out)
+	case cmdGetBalance:
+		err = parseFlags(getBalanceCmd, args[1:], cli.out)
+	case cmdSend:
+		err = parseFlags(sendCmd, args[1:], cli.out)
+	case cmdCreateWallet:
+		err = parseFlags(createWalletCmd, args[1:], cli.out)
+	case cmdCreateMultiSig:
+		err = parseFlags(createMultiSigCmd, args[1:], cli.out)
+	case cmdListAddresses:
+		err = parseFlags(listAddressesCmd, args[1:], cli.out)
+	case cmdGetSupply:
+		err = parseFlags(getSupplyCmd, args[1:], cli.out)
+	case cmdGenerate:
+		err = parseFlags(generateCmd, args[1:], cli.out)
+	case cmdGetTxOutInfo:
+		err = parseFlags(getTxOutInfoCmd, args[1:], cli.out)
+	case cmdDumpTxOutSet:
+		err = parseFlags(dumpTxOutSetCmd, args[1:], cli.out)
+	case cmdLoadTxOutSet:
+		err = parseFlags(loadTxOutSetCmd, args[1:], cli.out)
+	case cmdReindex:
+		err = parseFlags(reindexCmd, args[1:], cli.out)
+	case cmdCreatePSBT:
+		err = parseFlags(createPSBTCmd, args[1:], cli.out)
+	case cmdSignPSBT:
+		err = parseFlags(signPSBTCmd, args[1:], cli.out)
+	case cmdCombinePSBT:
+		err = parseFlags(combinePSBTCmd, args[1:], cli.out)
+	case cmdFinalizePSBT:
+		err = parseFlags(finalizePSBTCmd, args[1:], cli.out)
+	case cmdSendPSBT:
+		err = parseFlags(sendPSBTCmd, args[1:], cli.out)
+	case cmdCreateRawTx:
+		err = parseFlags(createRawTxCmd, args[1:], cli.out)
+	case cmdDecodeRawTx:
+		err = parseFlags(decodeRawTxCmd, args[1:], cli.out)
+	case cmdSignRawTx:
+		err = parseFlags(signRawTxCmd, args[1:], cli.out)
+	case cmdSendRawTx:
+		err = parseFlags(sendRawTxCmd, args[1:], cli.out)
+	case cmdListUnspent:
+		err = parseFlags(listUnspentCmd, args[1:], cli.out)
+	case cmdLockUnspent:
+		err = parseFlags(lockUnspentCmd, args[1:], cli.out)
+	case cmdListLocked:
+		err = parseFlags(listLockedCmd, args[1:], cli.out)
+	default:
+		err = fmt.Errorf("unkown cmd: %v", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	if printChainCmd.Parsed() {
+		return cli.printChain()
+	}
+	if getBalanceCmd.Parsed() {
+		return cli.getBalance(*getBalanceAddress)
+	}
+	if sendCmd.Parsed() {
+		if len(*sendFrom) == 0 {
+			return errors.New("from cannot be nil")
+		}
+		var payments []blockchain.Payment
+		if len(*sendOutputs) != 0 {
+			if len(*sendTo) != 0 {
+				return errors.New("to and outputs cannot be both set")
+			}
+			payments, err = parsePayments(strings.Split(*sendOutputs, ","))
+			if err != nil {
+				return err
+			}
+		} else {
+			if len(*sendTo) == 0 {
+				return errors.New("to cannot be nil")
+			}
+			if *sendAmount <= 0 {
+				return errors.New("amount must greater than 0")
+			}
+			payments = []blockchain.Payment{{Address: *sendTo, Amount: *sendAmount}}
+		}
+		if *sendFeeRate < 0 || *sendLongTermFeeRate < 0 {
+			return errors.New("fee rates cannot be negative")
+		}
+		selector, err := blockchain.ParseCoinSelector(*sendSelection)
+		if err != nil {
+			return err
+		}
+		fees := blockchain.Fees{Rate: *sendFeeRate, LongTermRate: *sendLongTermFeeRate}
+		cc := &blockchain.CoinControl{Change: *sendChange, Selector: selector, Fees: fees}
+		if len(*sendInputs) != 0 {
+			cc.Inputs, err = parseOutPoints(strings.Split(*sendInputs, ","))
+			if err != nil {
+				return err
+			}
+		}
+		return cli.send(*sendFrom, payments, cc)
+	}
+	if createWalletCmd.Parsed() {
+		keyType, err := wallet.ParseKeyType(*createWalletKey)
+		if err != nil {
+			return err
+		}
+		format, err := wallet.ParseAddressFormat(*createWalletType)
+		if err != nil {
+			return err
+		}
+		return cli.createWallet(keyType, format)
+	}
+	if createMultiSigCmd.Parsed() {
+		if len(*multiSigAddresses) == 0 {
+			return errors.New("addresses cannot be nil")
+		}
+		format, err := wallet.ParseAddressFormat(*multiSigType)
+		if err != nil {
+			return err
+		}
+		return cli.createMultiSig(*multiSigRequired, strings.Split(*multiSigAddresses, ","), format)
+	}
+	if listAddressesCmd.Parsed() {
+		return cli.listAddresses()
+	}
+	if getSupplyCmd.Parsed() {
+		return cli.getSupply()
+	}
+	if generateCmd.Parsed() {
+		if *generateNum <= 0 {
+			return errors.New("n must greater than 0")
+		}
+		return cli.generate(*generateNum, *generateAddress)
+	}
+	if getTxOutInfoCmd.Parsed() {
+		return cli.getTxOutSetInfo()
+	}
+	if dumpTxOutSetCmd.Parsed() {
+		if len(*dumpFile) == 0 {
+			return errors.New("file cannot be nil")
+		}
+		return cli.dumpTxOutSet(*dumpFile)
+	}
+	if loadTxOutSetCmd.Parsed() {
+		if len(*loadFile) == 0 {
+			return errors.New("file cannot be nil")
+		}
+		return cli.loadTxOutSet(*loadFile)
+	}
+	if reindexCmd.Parsed() {
+		return cli.reindex(*reindexFull)
+	}
+	if createPSBTCmd.Parsed() {
+		if *createPSBTAmount <= 0 {
+			return errors.New("amount must greater than 0")
+		}
+		hashType, err := blockchain.ParseSigHashType(*createPSBTSigHash)
+		if err != nil {
+			return err
+		}
+		return cli.createPSBT(*createPSBTFrom, *createPSBTTo, *createPSBTAmount, hashType)
+	}
+	if signPSBTCmd.Parsed() {
+		return cli.signPSBT(*signPSBT)
+	}
+	if combinePSBTCmd.Parsed() {
+		return cli.combinePSBT(strings.Split(*combinePSBTs, ","))
+	}
+	if finalizePSBTCmd.Parsed() {
+		return cli.finalizePSBT(*finalizePSBT)
+	}
+	if sendPSBTCmd.Parsed() {
+		return cli.sendPSBT(*sendPSBT, *sendPSBTAddress)
+	}
+	if createRawTxCmd.Parsed() {
+		if len(*createRawTxInputs) == 0 {
+			return errors.New("inputs cannot be nil")
+		}
+		if len(*createRawTxOutputs) == 0 {
+			return errors.New("outputs cannot be nil")
+		}
+		return cli.createRawTransaction(strings.Split(*createRawTxInputs, ","), strings.Split(*createRawTxOutputs, ","))
+	}
+	if decodeRawTxCmd.Parsed() {
+		return cli.decodeRawTransaction(*decodeRawTx)
+	}
+	if signRawTxCmd.Parsed() {
+		hashType, err := blockchain.ParseSigHashType(*signRawTxSigHash)
+		if err != nil {
+			return err
+		}
+		return cli.signRawTransaction(*signRawTx, hashType)
+	}
+	if sendRawTxCmd.Parsed() {
+		return cli.sendRawTransaction(*sendRawTx, *sendRawTxAddress)
+	}
+	if listUnspentCmd.Parsed() {
+		return cli.listUnspent(*listUnspentAddress)
+	}
+	if lockUnspentCmd.Parsed() {
+		if len(*lockUnspentOutputs) == 0 {
+			return errors.New("outputs cannot be nil")
+		}
+		return cli.lockUnspent(strings.Split(*lockUnspentOutputs, ","), *lockUnspentUnlock)
+	}
+	if listLockedCmd.Parsed() {
+		return cli.listLockUnspent()
+	}
+
+	return nil
+}
+
+func parseFlags(fs *flag.FlagSet, args []string, out io.Writer) error {
+	fs.SetOutput(out)
+	return fs.Parse(args)
+}
+
+// openChain returns the chain and UTXO set kept open by the daemon,
+// or opens the database directly. The returned function releases them.
+func (cli *CLI) openChain() (*blockchain.Blockchain, blockchain.UTxOSet, func(), error) {
+	if cli.node != nil {
+		return cli.node.bc, cli.node.utxo, func() {}, nil
+	}
+
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return nil, blockchain.UTxOSet{}, nil, err
+	}
+
+	return bc, blockchain.UTxOSet{BC: bc}, func() { bc.Close() }, nil
+}
+
+// openBlockchain opens the chain of the network, it is created from the
+// genesis block of the network when it does not exist yet
+func (cli *CLI) openBlockchain() (*blockchain.Blockchain, error) {
+	params := cli.params
+	store, err := cli.openStore()
+	if err != nil {
+		return nil, err
+	}
+
+	bc, err := blockchain.NewBlockchain(store, params)
+	if errors.Is(err, blockchain.ErrNoBlockchain) {
+		bc, err = blockchain.CreateBlockchain(store, "", params)
+	}
+	if errors.Is(err, blockchain.ErrUTXOMismatch) {
+		fmt.Fprintf(cli.out, "%v, reindexing\n", err)
+		bc, err = blockchain.ReindexBlockchain(store, params, blockchain.ReindexOptions{Progress: cli.reindexProgress})
+	}
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	bc.SetUTXOCacheSize(cli.dbCacheMB << 20)
+
+	return bc, nil
+}
+
+// reindexProgress prints the progress of a reindex to the command output
+func (cli *CLI) reindexProgress(height, tip int) error {
+	fmt.Fprintf(cli.out, "Reindexed %d/%d blocks\n", height, tip)
+	return nil
+}
+
+// openStore opens the database and block files of the network
+func (cli *CLI) openStore() (blockchain.Store, error) {
+	file, err := cli.params.DataFile(dbFile)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := cli.params.DataFile(blocksDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockchain.OpenFlatFileStore(file, dir, cli.pruneMB<<20)
+}
+
+// openWallets returns the wallets kept open by the daemon,
+// or loads them from the wallet file
+func (cli *CLI) openWallets() (*wallet.Wallets, error) {
+	if cli.node != nil {
+		return cli.node.wallets, nil
+	}
+
+	file, err := cli.params.DataFile(walletFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return wallet.NewWallets(file, cli.params)
+}
+
+func (cli *CLI) printChain() error {
+	chain, _, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	snapshotBase, err := chain.SnapshotBase()
+	if err != nil {
+		return err
+	}
+	iter := chain.Iterator()
+
+	for {
+		block, err := iter.Next()
+		pruned := errors.Is(err, blockchain.ErrPruned)
+		if pruned {
+			block, err = iter.NextHeader()
+		}
+		if errors.Is(err, blockchain.ErrNotFound) && snapshotBase != nil {
+			fmt.Fprintf(cli.out, "The blocks before the UTXO snapshot at %x are not validated yet\n", snapshotBase)
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cli.out, "============ Block %x ============\n", block.Hash)
+		fmt.Fprintf(cli.out, "Height: %d\n", block.Height)
+		fmt.Fprintf(cli.out, "Prev. block: %x\n", block.PrevBlockHash)
+		if pruned {
+			fmt.Fprintf(cli.out, "Transactions: pruned\n\n\n")
+		} else {
+			pow := blockchain.NewProofOfWork(block, cli.params.TargetBits)
+			fmt.Fprintf(cli.out, "PoW: %s\n\n", strconv.FormatBool(pow.Validate()))
+			for _, tx := range block.Transactions {
+				fmt.Fprintln(cli.out, tx)
+			}
+			fmt.Fprintf(cli.out, "\n\n")
+		}
+
+		if len(iter.CurrentHash()) == 0 {
+			break
+		}
+	}
+
+	return nil
+}
+
+func (cli *CLI) getBalance(address string) error {
+	pubKeyHash, err := wallet.PubKeyHashFromAddress(address, cli.params)
+	if err != nil {
+		return err
+	}
+	_, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	balance := 0
+
+	utxos, err := u.FindUTXO(pubKeyHash)
+	if err != nil {
+		return err
+	}
+	for _, out := range utxos {
+		balance += out.Value
+	}
+
+	fmt.Fprintf(cli.out, "Balance of '%v' : %d\n", address, balance)
+	return nil
+}
+
+// send mines a block of a transaction paying every payment from an
+// address, the coin control picks the outputs it spends
+func (cli *CLI) send(from string, payments []blockchain.Payment, cc *blockchain.CoinControl) error {
+	if !wallet.ValidateAddress(from, cli.params) {
+		return errors.New("ERROR: Sender address is not valid")
+	}
+	for _, p := range payments {
+		if !wallet.ValidateAddress(p.Address, cli.params) {
+			return errors.New("ERROR: Recipient address is not valid")
+		}
+	}
+	if len(cc.Change) != 0 && !wallet.ValidateAddress(cc.Change, cli.params) {
+		return errors.New("ERROR: Change address is not valid")
+	}
+
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+	spender, err := wallets.Spender(from)
+	if err != nil {
+		return err
+	}
+	cc.Locked = wallets.LockedOutputs()
+
+	bc, UTXOSET, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	tx, err := blockchain.NewSendTransaction(spender, payments, cc, &UTXOSET)
+	if err != nil {
+		return err
+	}
+	err = mineTransaction(bc, tx, from)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, "success")
+	return nil
+}
+
+// mineTransaction mines a block of the transaction, the block reward goes
+// to the address
+func mineTransaction(bc *blockchain.Blockchain, tx *blockchain.Transaction, address string) error {
+	height, err := bc.GetBestHeight()
+	if err != nil {
+		return err
+	}
+	fee, err := bc.TransactionFee(tx)
+	if err != nil {
+		return err
+	}
+	cbTx, err := blockchain.NewCoinbaseTX(address, "", height+1, fee, bc.Params())
+	if err != nil {
+		return err
+	}
+
+	_, err = bc.AddBlock([]*blockchain.Transaction{cbTx, tx})
+
+	return err
+}
+
+func (cli *CLI) createWallet(keyType wallet.KeyType, format wallet.AddressFormat) error {
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+	address, err := wallets.CreateWallet(keyType, format)
+	if err != nil {
+		return err
+	}
+	err = wallets.SaveToFile()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "Your new address: %s\n", address)
+	return nil
+}
+
+func (cli *CLI) createMultiSig(required int, addresses []string, format wallet.AddressFormat) error {
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+	address, err := wallets.CreateMultiSig(required, addresses, format)
+	if err != nil {
+		return err
+	}
+	err = wallets.SaveToFile()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "Your new script address: %s\n", address)
+	return nil
+}
+
+func (cli *CLI) listAddresses() error {
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+	addresses := wallets.Addresses()
+
+	fmt.Fprintln(cli.out, "Your wallets address list:")
+	for _, address := range addresses {
+		fmt.Fprintln(cli.out, "		", address)
+	}
+
+	return nil
+}
+
+func (cli *CLI) getSupply() error {
+	bc, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	height, err := bc.GetBestHeight()
+	if err != nil {
+		return err
+	}
+	issued, err := bc.IssuedSupply()
+	pruned := errors.Is(err, blockchain.ErrPruned)
+	if err != nil && !pruned {
+		return err
+	}
+	unspent, err := u.TotalAmount()
+	if err != nil {
+		return err
+	}
+
+	emission := cli.params.Emission
+	fmt.Fprintf(cli.out, "Height:           %d\n", height)
+	fmt.Fprintf(cli.out, "Block subsidy:    %d\n", emission.Subsidy(height+1))
+	if pruned {
+		fmt.Fprintf(cli.out, "Issued:           unknown, blocks are pruned\n")
+	} else {
+		fmt.Fprintf(cli.out, "Issued:           %d\n", issued)
+	}
+	fmt.Fprintf(cli.out, "Scheduled supply: %d\n", emission.Supply(height))
+	fmt.Fprintf(cli.out, "Max supply:       %d\n", emission.MaxSupply)
+	fmt.Fprintf(cli.out, "UTXO set total:   %d\n", unspent)
+
+	if !pruned && issued != unspent {
+		return fmt.Errorf("issued amount %d does not match UTXO set total %d", issued, unspent)
+	}
+
+	return nil
+}
+
+func (cli *CLI) generate(n int, address string) error {
+	if !cli.params.GenerateSupported {
+		return fmt.Errorf("generate is not supported on %s", cli.params.Name)
+	}
+	if !wallet.ValidateAddress(address, cli.params) {
+		return errors.New("ERROR: Address is not valid")
+	}
+
+	bc, _, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for i := 0; i < n; i++ {
+		height, err := bc.GetBestHeight()
+		if err != nil {
+			return err
+		}
+		cbTx, err := blockchain.NewCoinbaseTX(address, "", height+1, 0, cli.params)
+		if err != nil {
+			return err
+		}
+		newBlock, err := bc.AddBlock([]*blockchain.Transaction{cbTx})
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cli.out, "%x\n", newBlock.Hash)
+	}
+
+	return nil
+}
+
+func (cli *CLI) getTxOutSetInfo() error {
+	_, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	stats, err := u.Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "Best block:   %x\n", stats.BestBlock)
+	fmt.Fprintf(cli.out, "Height:       %d\n", stats.Height)
+	fmt.Fprintf(cli.out, "Transactions: %d\n", stats.Transactions)
+	fmt.Fprintf(cli.out, "Outputs:      %d\n", stats.Outputs)
+	fmt.Fprintf(cli.out, "Total amount: %d\n", stats.TotalAmount)
+	fmt.Fprintf(cli.out, "Hash:         %x\n", stats.Hash)
+
+	return nil
+}
+
+func (cli *CLI) dumpTxOutSet(file string) error {
+	bc, _, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	stats, err := bc.DumpUTXOSnapshot(f)
+	if err != nil {
+		f.Close()
+		os.Remove(file)
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "Base block: %x\n", stats.BestBlock)
+	fmt.Fprintf(cli.out, "Height:     %d\n", stats.Height)
+	fmt.Fprintf(cli.out, "Outputs:    %d\n", stats.Outputs)
+	fmt.Fprintf(cli.out, "Hash:       %x\n", stats.Hash)
+
+	return nil
+}
+
+// loadTxOutSet starts the chain of the network from a snapshot, the
+// history can be validated later by a daemon started with -history
+func (cli *CLI) loadTxOutSet(file string) error {
+	if cli.node != nil {
+		return errors.New("the daemon already has a chain")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	store, err := cli.openStore()
+	if err != nil {
+		return err
+	}
+	bc, err := blockchain.LoadUTXOSnapshot(store, f, cli.params)
+	if err != nil {
+		store.Close()
+		return err
+	}
+	defer bc.Close()
+
+	height, err := bc.GetBestHeight()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "Loaded the UTXO set at height %d\n", height)
+	return nil
+}
+
+func (cli *CLI) reindex(full bool) error {
+	_, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = u.Reindex(blockchain.ReindexOptions{
+		Full:     full,
+		Progress: cli.reindexProgress,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, "success")
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/MikasaAkerman/blockchain-go/blockchain"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// listUnspent prints the unspent outputs of an address, or of every
+// address of the wallets, and whether they are locked
+func (cli *CLI) listUnspent(address string) error {
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+	addresses := []string{address}
+	if len(address) == 0 {
+		addresses = wallets.Addresses()
+		sort.Strings(addresses)
+	}
+	locked := wallets.LockedOutputs()
+
+	_, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, address := range addresses {
+		keyType, pubKeyHash, err := wallet.DecodeAddress(address, cli.params)
+		if err != nil {
+			return err
+		}
+		unspent, err := u.ListUnspent(pubKeyHash)
+		if err != nil {
+			return err
+		}
+		for _, uo := range unspent {
+			if uo.Output.KeyType != keyType {
+				continue
+			}
+			line := fmt.Sprintf("%s %s %d", uo.OutPoint, address, uo.Output.Value)
+			if locked[uo.OutPoint.String()] {
+				line += " locked"
+			}
+			fmt.Fprintln(cli.out, line)
+		}
+	}
+
+	return nil
+}
+
+// lockUnspent locks the outputs from being selected by send, or unlocks
+// them. Only unspent outputs can be locked.
+func (cli *CLI) lockUnspent(outpoints []string, unlock bool) error {
+	ops, err := parseOutPoints(outpoints)
+	if err != nil {
+		return err
+	}
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+
+	_, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, op := range ops {
+		if unlock {
+			wallets.UnlockOutput(op.String())
+			continue
+		}
+		_, err := u.Output(op)
+		if errors.Is(err, blockchain.ErrNotFound) {
+			return fmt.Errorf("output %s: %w", op, blockchain.ErrMissingInput)
+		}
+		if err != nil {
+			return err
+		}
+		wallets.LockOutput(op.String())
+	}
+	_, err = dropSpentLocks(wallets, u)
+	if err != nil {
+		return err
+	}
+	err = wallets.SaveToFile()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, "success")
+	return nil
+}
+
+// listLockUnspent prints the outpoints of the locked outputs, the locks of
+// the outputs spent since are dropped
+func (cli *CLI) listLockUnspent() error {
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+
+	_, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	dropped, err := dropSpentLocks(wallets, u)
+	if err != nil {
+		return err
+	}
+	if dropped {
+		err = wallets.SaveToFile()
+		if err != nil {
+			return err
+		}
+	}
+
+	var outpoints []string
+	for op := range wallets.LockedOutputs() {
+		outpoints = append(outpoints, op)
+	}
+	sort.Strings(outpoints)
+	for _, op := range outpoints {
+		fmt.Fprintln(cli.out, op)
+	}
+
+	return nil
+}
+
+// dropSpentLocks unlocks the locked outputs which are no longer in the
+// UTXO set, and reports whether there were any
+func dropSpentLocks(wallets *wallet.Wallets, u blockchain.UTxOSet) (bool, error) {
+	dropped := false
+	for outpoint := range wallets.LockedOutputs() {
+		op, err := blockchain.ParseOutPoint(outpoint)
+		if err != nil {
+			return false, err
+		}
+		_, err = u.Output(op)
+		if errors.Is(err, blockchain.ErrNotFound) {
+			wallets.UnlockOutput(outpoint)
+			dropped = true
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return dropped, nil
+}
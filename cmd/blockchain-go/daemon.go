@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/MikasaAkerman/blockchain-go/blockchain"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// daemonSocket the unix socket a daemon listens on, in the data directory
+const daemonSocket = "node.sock"
+
+// node holds the resources a daemon keeps open between commands
+type node struct {
+	mu      sync.Mutex
+	bc      *blockchain.Blockchain
+	utxo    blockchain.UTxOSet
+	wallets *wallet.Wallets
+}
+
+// Daemon the RPC service of a running daemon
+type Daemon struct {
+	cli *CLI
+}
+
+// CommandArgs a command forwarded to the daemon
+type CommandArgs struct {
+	Args []string
+	// Flags the global flags set on the command line of the client,
+	// they must match the daemon's
+	Flags map[string]string
+}
+
+// fileFlags the flags naming files, by command. The client makes them
+// absolute, the daemon may run in another directory.
+var fileFlags = map[string][]string{
+	cmdDumpTxOutSet: {"file"},
+	cmdLoadTxOutSet: {"file"},
+}
+
+// CommandReply the result of a command executed by the daemon
+type CommandReply struct {
+	Output string
+	Error  string
+}
+
+// Exec executes a CLI command inside the daemon, commands run one at a time
+func (d *Daemon) Exec(cmd CommandArgs, reply *CommandReply) error {
+	d.cli.node.mu.Lock()
+	defer d.cli.node.mu.Unlock()
+
+	// the daemon opened the chain with its own flags
+	for name, value := range cmd.Flags {
+		var f *flag.Flag
+		if d.cli.globals != nil {
+			f = d.cli.globals.Lookup(name)
+		}
+		if f == nil || f.Value.String() != value {
+			reply.Error = fmt.Sprintf("-%s %s does not match the daemon, restart it with the flag", name, value)
+			return nil
+		}
+	}
+
+	var out bytes.Buffer
+	defer func() {
+		// a failing command must not take the daemon down
+		if r := recover(); r != nil {
+			reply.Output = out.String()
+			reply.Error = fmt.Sprint(r)
+		}
+	}()
+
+	cli := &CLI{out: &out, params: d.cli.params, pruneMB: d.cli.pruneMB, dbCacheMB: d.cli.dbCacheMB, node: d.cli.node}
+	err := cli.execute(cmd.Args)
+
+	reply.Output = out.String()
+	if err != nil {
+		reply.Error = err.Error()
+	}
+
+	return nil
+}
+
+// runDaemon opens the chain and wallets of the active network and serves
+// the commands forwarded by the CLI until the process is interrupted
+func (cli *CLI) runDaemon(args []string) error {
+	daemonCmd := flag.NewFlagSet(cmdDaemon, flag.ExitOnError)
+	history := daemonCmd.String("history", "", "The data directory of a node holding the blocks before a loaded UTXO snapshot")
+	err := daemonCmd.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	socket, err := cli.params.DataFile(daemonSocket)
+	if err != nil {
+		return err
+	}
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return errors.New("a daemon is already running")
+	}
+
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+	bc, err := cli.openBlockchain()
+	if err != nil {
+		return err
+	}
+	cli.node = &node{bc: bc, utxo: blockchain.UTxOSet{BC: bc}, wallets: wallets}
+
+	// the socket of a daemon that did not exit cleanly
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		bc.Close()
+		return err
+	}
+
+	server := rpc.NewServer()
+	err = server.Register(&Daemon{cli})
+	if err != nil {
+		listener.Close()
+		bc.Close()
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupt
+		listener.Close()
+	}()
+
+	if len(*history) != 0 {
+		go validateSnapshot(bc, *history)
+	}
+
+	log.Printf("daemon of %s listening on %s", cli.params.Name, socket)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			break
+		}
+		go server.ServeConn(conn)
+	}
+
+	// wait for the running command before closing the database
+	cli.node.mu.Lock()
+	defer cli.node.mu.Unlock()
+	log.Println("daemon stopped")
+
+	return bc.Close()
+}
+
+// validateSnapshot validates the blocks before the UTXO snapshot the chain
+// was loaded from, reading them from the data directory of another node
+func validateSnapshot(bc *blockchain.Blockchain, dir string) {
+	base, err := bc.SnapshotBase()
+	if err != nil {
+		log.Printf("snapshot validation: %v", err)
+		return
+	}
+	if base == nil {
+		return
+	}
+
+	history, err := blockchain.OpenFlatFileStore(filepath.Join(dir, dbFile), filepath.Join(dir, blocksDir), 0)
+	if err != nil {
+		log.Printf("snapshot validation: %v", err)
+		return
+	}
+	defer history.Close()
+
+	log.Printf("validating the blocks before snapshot %x", base)
+	err = bc.ValidateSnapshot(history)
+	if err != nil {
+		log.Printf("snapshot validation failed: %v", err)
+		return
+	}
+	log.Printf("snapshot %x validated", base)
+}
+
+// callDaemon forwards a command to the daemon of the active network,
+// it reports false when no daemon is running
+func (cli *CLI) callDaemon(args []string) (bool, error) {
+	socket, err := cli.params.DataFile(daemonSocket)
+	if err != nil {
+		return false, err
+	}
+	client, err := rpc.Dial("unix", socket)
+	if err != nil {
+		return false, nil
+	}
+	defer client.Close()
+
+	cmd := CommandArgs{Args: args, Flags: make(map[string]string)}
+	if cli.globals != nil {
+		cli.globals.Visit(func(f *flag.Flag) {
+			// the network picks the daemon
+			if f.Name != "network" {
+				cmd.Flags[f.Name] = f.Value.String()
+			}
+		})
+	}
+	cmd.Args, err = absFileArgs(args)
+	if err != nil {
+		return true, err
+	}
+
+	var reply CommandReply
+	err = client.Call("Daemon.Exec", cmd, &reply)
+	if err != nil {
+		return true, err
+	}
+
+	io.WriteString(cli.out, reply.Output)
+	if len(reply.Error) != 0 {
+		return true, errors.New(reply.Error)
+	}
+
+	return true, nil
+}
+
+// absFileArgs returns the arguments of a command with the values of its
+// fileFlags made absolute
+func absFileArgs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+
+	abs := append([]string{}, args...)
+	for i := 1; i < len(abs); i++ {
+		name, value, inline := strings.Cut(strings.TrimLeft(abs[i], "-"), "=")
+		if !strings.HasPrefix(abs[i], "-") || !isFileFlag(abs[0], name) {
+			continue
+		}
+		if !inline {
+			i++
+			if i == len(abs) {
+				break
+			}
+			value = abs[i]
+		}
+
+		path, err := filepath.Abs(value)
+		if err != nil {
+			return nil, err
+		}
+		if inline {
+			abs[i] = "-" + name + "=" + path
+		} else {
+			abs[i] = path
+		}
+	}
+
+	return abs, nil
+}
+
+func isFileFlag(cmd, name string) bool {
+	for _, f := range fileFlags[cmd] {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+// testCLI returns a CLI working on regtest in a temporary data directory
+func testCLI(t *testing.T) (*CLI, *bytes.Buffer) {
+	params := chaincfg.RegTestParams
+	params.DataDir = t.TempDir()
+
+	var out bytes.Buffer
+	return &CLI{out: &out, params: &params, dbCacheMB: 1}, &out
+}
+
+func TestDaemon(t *testing.T) {
+	daemon, _ := testCLI(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- daemon.runDaemon(nil)
+	}()
+
+	socket, err := daemon.params.DataFile(daemonSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; ; i++ {
+		conn, err := net.Dial("unix", socket)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatalf("the daemon did not start: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var out bytes.Buffer
+	client := &CLI{out: &out, params: daemon.params}
+	run := func(args ...string) string {
+		t.Helper()
+		out.Reset()
+		forwarded, err := client.callDaemon(args)
+		if !forwarded || err != nil {
+			t.Fatalf("%s: forwarded %v, %v", args[0], forwarded, err)
+		}
+		return out.String()
+	}
+
+	var address string
+	fmt.Sscanf(run(cmdCreateWallet), "Your new address: %s", &address)
+	run(cmdGenerate, "-address", address, "-n", "2")
+	want := fmt.Sprintf("Balance of '%s' : %d\n", address, 2*daemon.params.Emission.Subsidy(1))
+	if got := run(cmdGetBalance, "-address", address); got != want {
+		t.Errorf("balance through the daemon: %q, want %q", got, want)
+	}
+
+	// the daemon keeps the flags it was started with
+	client.globals = flag.NewFlagSet("client", flag.ContinueOnError)
+	client.globals.Uint64("prune", 0, "")
+	client.globals.Parse([]string{"-prune", "600"})
+	if _, err := client.callDaemon([]string{cmdGetSupply}); err == nil || !strings.Contains(err.Error(), "-prune") {
+		t.Errorf("command with another -prune: %v", err)
+	}
+
+	syscall.Kill(os.Getpid(), syscall.SIGINT)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon did not stop")
+	}
+}
+
+func TestNoDaemon(t *testing.T) {
+	cli, out := testCLI(t)
+
+	// the socket left behind by a daemon that did not exit cleanly
+	socket, err := cli.params.DataFile(daemonSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stale := range []bool{false, true} {
+		if stale {
+			os.WriteFile(socket, nil, 0600)
+		}
+		forwarded, err := cli.callDaemon([]string{cmdGetSupply})
+		if forwarded || err != nil {
+			t.Fatalf("stale socket %v: forwarded %v, %v", stale, forwarded, err)
+		}
+	}
+
+	// the command then opens the database itself
+	if err := cli.execute([]string{cmdGetSupply}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "Height:           0\n") {
+		t.Errorf("getsupply without a daemon: %q", out.String())
+	}
+}
+
+func TestAbsFileArgs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := filepath.Join(wd, "snap")
+
+	for _, tc := range []struct {
+		args, want []string
+	}{
+		{[]string{cmdDumpTxOutSet, "-file", "snap"}, []string{cmdDumpTxOutSet, "-file", snap}},
+		{[]string{cmdLoadTxOutSet, "--file=snap"}, []string{cmdLoadTxOutSet, "-file=" + snap}},
+		{[]string{cmdDumpTxOutSet, "-file", "/tmp/snap"}, []string{cmdDumpTxOutSet, "-file", "/tmp/snap"}},
+		{[]string{cmdDumpTxOutSet, "-file"}, []string{cmdDumpTxOutSet, "-file"}},
+		{[]string{cmdGetBalance, "-address", "snap"}, []string{cmdGetBalance, "-address", "snap"}},
+	} {
+		got, err := absFileArgs(tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(got, " ") != strings.Join(tc.want, " ") {
+			t.Errorf("absFileArgs(%q) = %q, want %q", tc.args, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+const (
+	dbFile     = "block-chain.db"
+	blocksDir  = "blocks"
+	walletFile = "wallet.db"
+)
+
+func main() {
+	cli := NewCLI()
+	cli.Run()
+}
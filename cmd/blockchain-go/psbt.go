@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/MikasaAkerman/blockchain-go/blockchain"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// createPSBT prints a partially signed transaction paying amount from the
+// outputs of an address, no key of it is needed
+func (cli *CLI) createPSBT(from, to string, amount int, hashType blockchain.SigHashType) error {
+	keyType, pubKeyHash, err := wallet.DecodeAddress(from, cli.params)
+	if err != nil {
+		return fmt.Errorf("sender: %w", err)
+	}
+	if !wallet.ValidateAddress(to, cli.params) {
+		return errors.New("ERROR: Recipient address is not valid")
+	}
+
+	bc, u, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	tx, err := blockchain.NewUnsignedTransaction(keyType, pubKeyHash, []blockchain.Payment{{Address: to, Amount: amount}}, nil, &u)
+	if err != nil {
+		return err
+	}
+	p, err := bc.NewPSBT(tx, hashType)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, p.Encode())
+	return nil
+}
+
+// signPSBT adds the signatures of the wallets to a partially signed
+// transaction, it only needs the wallet file
+func (cli *CLI) signPSBT(encoded string) error {
+	p, err := blockchain.DecodePSBT(encoded)
+	if err != nil {
+		return err
+	}
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+
+	n, err := p.Sign(wallets)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.out, p.Encode())
+	fmt.Fprintf(cli.out, "added %d signatures\n", n)
+	return nil
+}
+
+// combinePSBT merges the signatures of partially signed transactions of
+// the same transaction
+func (cli *CLI) combinePSBT(encoded []string) error {
+	var psbts []*blockchain.PSBT
+	for _, s := range encoded {
+		p, err := blockchain.DecodePSBT(s)
+		if err != nil {
+			return err
+		}
+		psbts = append(psbts, p)
+	}
+
+	err := psbts[0].Combine(psbts[1:]...)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, psbts[0].Encode())
+	return nil
+}
+
+// finalizePSBT fills the inputs of a partially signed transaction with
+// the signatures collected
+func (cli *CLI) finalizePSBT(encoded string) error {
+	p, err := blockchain.DecodePSBT(encoded)
+	if err != nil {
+		return err
+	}
+	err = p.Finalize()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, p.Encode())
+	return nil
+}
+
+// sendPSBT mines a block of a partially signed transaction, finalizing it
+// when it is not yet
+func (cli *CLI) sendPSBT(encoded, address string) error {
+	if !wallet.ValidateAddress(address, cli.params) {
+		return errors.New("ERROR: Address is not valid")
+	}
+	p, err := blockchain.DecodePSBT(encoded)
+	if err != nil {
+		return err
+	}
+	if !p.Complete() {
+		err = p.Finalize()
+		if err != nil {
+			return err
+		}
+	}
+
+	bc, _, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = mineTransaction(bc, &p.Tx, address)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "%x\n", p.Tx.ID)
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/MikasaAkerman/blockchain-go/blockchain"
+	"github.com/MikasaAkerman/blockchain-go/wallet"
+)
+
+// createRawTransaction prints the hex of an unsigned transaction spending
+// the outpoints, written txid:vout, to the outputs, written address:amount
+func (cli *CLI) createRawTransaction(inputs, outputs []string) error {
+	ops, err := parseOutPoints(inputs)
+	if err != nil {
+		return err
+	}
+	payments, err := parsePayments(outputs)
+	if err != nil {
+		return err
+	}
+
+	var outs []blockchain.TxOutput
+	for _, p := range payments {
+		out, err := blockchain.NewTxOutput(p.Amount, p.Address, cli.params)
+		if err != nil {
+			return fmt.Errorf("output %s: %w", p.Address, err)
+		}
+		outs = append(outs, *out)
+	}
+
+	tx, err := blockchain.NewRawTransaction(ops, outs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, tx.Hex())
+	return nil
+}
+
+// parseOutPoints parses outpoints written txid:vout
+func parseOutPoints(inputs []string) ([]blockchain.OutPoint, error) {
+	var ops []blockchain.OutPoint
+	for _, s := range inputs {
+		op, err := blockchain.ParseOutPoint(s)
+		if err != nil {
+			return nil, err
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// parsePayments parses payments written address:amount
+func parsePayments(outputs []string) ([]blockchain.Payment, error) {
+	var payments []blockchain.Payment
+	for _, s := range outputs {
+		address, value, ok := strings.Cut(s, ":")
+		if !ok {
+			return nil, fmt.Errorf("output %q lacks an amount", s)
+		}
+		amount, err := strconv.Atoi(value)
+		if err != nil || amount <= 0 {
+			return nil, fmt.Errorf("output %q: amount must greater than 0", s)
+		}
+		payments = append(payments, blockchain.Payment{Address: address, Amount: amount})
+	}
+
+	return payments, nil
+}
+
+// decodeRawTransaction prints a raw transaction in a human-readable form
+func (cli *CLI) decodeRawTransaction(encoded string) error {
+	tx, err := blockchain.DecodeRawTransaction(encoded)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cli.out, tx)
+	return nil
+}
+
+// signRawTransaction signs the inputs of a raw transaction the wallets
+// hold the keys of, and prints it
+func (cli *CLI) signRawTransaction(encoded string, hashType blockchain.SigHashType) error {
+	tx, err := blockchain.DecodeRawTransaction(encoded)
+	if err != nil {
+		return err
+	}
+	wallets, err := cli.openWallets()
+	if err != nil {
+		return err
+	}
+
+	bc, _, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	complete, err := bc.SignRawTransaction(tx, wallets, hashType)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cli.out, tx.Hex())
+	if !complete {
+		fmt.Fprintln(cli.out, "some inputs are not signed")
+	}
+
+	return nil
+}
+
+// sendRawTransaction mines a block of a signed raw transaction
+func (cli *CLI) sendRawTransaction(encoded, address string) error {
+	if !wallet.ValidateAddress(address, cli.params) {
+		return errors.New("ERROR: Address is not valid")
+	}
+	tx, err := blockchain.DecodeRawTransaction(encoded)
+	if err != nil {
+		return err
+	}
+
+	bc, _, release, err := cli.openChain()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	err = mineTransaction(bc, tx, address)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cli.out, "%x\n", tx.ID)
+	return nil
+}
@@ -1,16 +0,0 @@
-package main
-
-const (
-	dbFile              = "block-chain.db"
-	utxoBucket          = "utxoBucket"
-	blocksBucket        = "blocksBucket"
-	subsidy             = 50
-	genesisCoinbaseData = "Genesis data"
-	version             = byte(0x00)
-	addressChecksumLen  = 4
-)
-
-func main() {
-	cli := NewCLI()
-	cli.Run()
-}
@@ -0,0 +1,314 @@
+// Package secp256k1 implements the secp256k1 curve with ECDSA and BIP340
+// Schnorr signatures over it. The arithmetic uses math/big and does not
+// run in constant time.
+package secp256k1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("secp256k1: bad constant " + s)
+	}
+
+	return n
+}
+
+var (
+	// P the order of the field
+	P = fromHex("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEFFFFFC2F")
+	// N the order of the group
+	N = fromHex("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141")
+	// Gx and Gy the generator
+	Gx = fromHex("79BE667EF9DCBBAC55A06295CE870B07029BFCDB2DCE28D959F2815B16F81798")
+	Gy = fromHex("483ADA7726A3C4655DA4FBFC0E1108A8FD17B448A68554199C47D08FFB10D4B8")
+
+	// halfN the largest s of a low-S signature
+	halfN = new(big.Int).Rsh(N, 1)
+	// sqrtExp raises to it to take a square root, P is 3 mod 4
+	sqrtExp = new(big.Int).Rsh(new(big.Int).Add(P, big.NewInt(1)), 2)
+)
+
+var (
+	// ErrInvalidPubKey a public key is malformed or not on the curve
+	ErrInvalidPubKey = errors.New("secp256k1: invalid public key")
+	// ErrInvalidPrivKey a private key is not in [1, N-1]
+	ErrInvalidPrivKey = errors.New("secp256k1: invalid private key")
+)
+
+// PublicKey a point of the curve
+type PublicKey struct {
+	X, Y *big.Int
+}
+
+// PrivateKey a scalar and its public key
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+// GenerateKey creates a private key from the random source
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	for {
+		d, err := randScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		k, err := NewPrivateKey(d.FillBytes(make([]byte, 32)))
+		if err == nil {
+			return k, nil
+		}
+	}
+}
+
+// randScalar reads a number in [1, N-1]
+func randScalar(rand io.Reader) (*big.Int, error) {
+	b := make([]byte, 32)
+	for {
+		_, err := io.ReadFull(rand, b)
+		if err != nil {
+			return nil, err
+		}
+		k := new(big.Int).SetBytes(b)
+		if k.Sign() > 0 && k.Cmp(N) < 0 {
+			return k, nil
+		}
+	}
+}
+
+// NewPrivateKey returns the private key of a 32-byte big-endian scalar
+func NewPrivateKey(d []byte) (*PrivateKey, error) {
+	if len(d) != 32 {
+		return nil, fmt.Errorf("%w: length %d", ErrInvalidPrivKey, len(d))
+	}
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() == 0 || k.Cmp(N) >= 0 {
+		return nil, ErrInvalidPrivKey
+	}
+
+	x, y := ScalarBaseMult(k)
+	return &PrivateKey{PublicKey{x, y}, k}, nil
+}
+
+// Bytes returns the 32-byte scalar of the key
+func (k *PrivateKey) Bytes() []byte {
+	return k.D.FillBytes(make([]byte, 32))
+}
+
+// SerializeCompressed returns the parity of Y and X, 33 bytes
+func (k *PublicKey) SerializeCompressed() []byte {
+	d := make([]byte, 33)
+	d[0] = 0x02 + byte(k.Y.Bit(0))
+	k.X.FillBytes(d[1:])
+
+	return d
+}
+
+// SerializeXOnly returns X, the form of BIP340 keys
+func (k *PublicKey) SerializeXOnly() []byte {
+	return k.X.FillBytes(make([]byte, 32))
+}
+
+// ParsePubKey parses a compressed public key
+func ParsePubKey(d []byte) (*PublicKey, error) {
+	if len(d) != 33 || (d[0] != 0x02 && d[0] != 0x03) {
+		return nil, fmt.Errorf("%w: not a compressed key", ErrInvalidPubKey)
+	}
+
+	x, y, ok := liftX(d[1:])
+	if !ok {
+		return nil, fmt.Errorf("%w: not on the curve", ErrInvalidPubKey)
+	}
+	if y.Bit(0) != uint(d[0]-0x02) {
+		y.Sub(P, y)
+	}
+
+	return &PublicKey{x, y}, nil
+}
+
+// ParseXOnlyPubKey parses a BIP340 public key, the point with an even Y
+func ParseXOnlyPubKey(d []byte) (*PublicKey, error) {
+	if len(d) != 32 {
+		return nil, fmt.Errorf("%w: length %d", ErrInvalidPubKey, len(d))
+	}
+
+	x, y, ok := liftX(d)
+	if !ok {
+		return nil, fmt.Errorf("%w: not on the curve", ErrInvalidPubKey)
+	}
+
+	return &PublicKey{x, y}, nil
+}
+
+// liftX returns the point of the X coordinate with an even Y
+func liftX(d []byte) (*big.Int, *big.Int, bool) {
+	x := new(big.Int).SetBytes(d)
+	if x.Cmp(P) >= 0 {
+		return nil, nil, false
+	}
+
+	// y^2 = x^3 + 7
+	c := new(big.Int).Exp(x, big.NewInt(3), P)
+	c.Add(c, big.NewInt(7)).Mod(c, P)
+	y := new(big.Int).Exp(c, sqrtExp, P)
+	if new(big.Int).Exp(y, big.NewInt(2), P).Cmp(c) != 0 {
+		return nil, nil, false
+	}
+	if y.Bit(0) == 1 {
+		y.Sub(P, y)
+	}
+
+	return x, y, true
+}
+
+// IsOnCurve reports whether the point is on the curve
+func IsOnCurve(x, y *big.Int) bool {
+	if x.Sign() < 0 || x.Cmp(P) >= 0 || y.Sign() < 0 || y.Cmp(P) >= 0 {
+		return false
+	}
+
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, P)
+	x3 := new(big.Int).Exp(x, big.NewInt(3), P)
+	x3.Add(x3, big.NewInt(7)).Mod(x3, P)
+
+	return y2.Cmp(x3) == 0
+}
+
+// jacobian a point as (X/Z^2, Y/Z^3), Z is 0 at infinity
+type jacobian struct {
+	x, y, z *big.Int
+}
+
+func fromAffine(x, y *big.Int) jacobian {
+	return jacobian{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+func infinity() jacobian {
+	return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+}
+
+func (p jacobian) isInfinity() bool {
+	return p.z.Sign() == 0
+}
+
+// affine returns the point as x, y, nil at infinity
+func (p jacobian) affine() (*big.Int, *big.Int) {
+	if p.isInfinity() {
+		return nil, nil
+	}
+
+	zInv := new(big.Int).ModInverse(p.z, P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x := new(big.Int).Mul(p.x, zInv2)
+	x.Mod(x, P)
+	y := zInv2.Mul(zInv2, zInv).Mul(zInv2, p.y)
+	y.Mod(y, P)
+
+	return x, y
+}
+
+func mulMod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Mul(a, b)
+	return r.Mod(r, P)
+}
+
+func subMod(a, b *big.Int) *big.Int {
+	r := new(big.Int).Sub(a, b)
+	return r.Mod(r, P)
+}
+
+func (p jacobian) double() jacobian {
+	if p.isInfinity() || p.y.Sign() == 0 {
+		return infinity()
+	}
+
+	a := mulMod(p.x, p.x)
+	b := mulMod(p.y, p.y)
+	c := mulMod(b, b)
+	// d = 2((x+b)^2 - a - c)
+	d := new(big.Int).Add(p.x, b)
+	d = subMod(subMod(mulMod(d, d), a), c)
+	d.Lsh(d, 1).Mod(d, P)
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	e.Mod(e, P)
+	f := mulMod(e, e)
+
+	x3 := subMod(f, new(big.Int).Lsh(d, 1))
+	y3 := subMod(mulMod(e, subMod(d, x3)), new(big.Int).Lsh(c, 3))
+	z3 := mulMod(p.y, p.z)
+	z3.Lsh(z3, 1).Mod(z3, P)
+
+	return jacobian{x3, y3, z3}
+}
+
+func (p jacobian) add(q jacobian) jacobian {
+	if p.isInfinity() {
+		return q
+	}
+	if q.isInfinity() {
+		return p
+	}
+
+	z1z1 := mulMod(p.z, p.z)
+	z2z2 := mulMod(q.z, q.z)
+	u1 := mulMod(p.x, z2z2)
+	u2 := mulMod(q.x, z1z1)
+	s1 := mulMod(mulMod(p.y, q.z), z2z2)
+	s2 := mulMod(mulMod(q.y, p.z), z1z1)
+
+	h := subMod(u2, u1)
+	if h.Sign() == 0 {
+		if s1.Cmp(s2) == 0 {
+			return p.double()
+		}
+		return infinity()
+	}
+
+	i := new(big.Int).Lsh(h, 1)
+	i = mulMod(i, i)
+	j := mulMod(h, i)
+	r := subMod(s2, s1)
+	r.Lsh(r, 1).Mod(r, P)
+	v := mulMod(u1, i)
+
+	x3 := subMod(subMod(mulMod(r, r), j), new(big.Int).Lsh(v, 1))
+	y3 := subMod(mulMod(r, subMod(v, x3)), new(big.Int).Lsh(mulMod(s1, j), 1))
+	z3 := new(big.Int).Add(p.z, q.z)
+	z3 = mulMod(subMod(subMod(mulMod(z3, z3), z1z1), z2z2), h)
+
+	return jacobian{x3, y3, z3}
+}
+
+func (p jacobian) mul(k *big.Int) jacobian {
+	r := infinity()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = r.double()
+		if k.Bit(i) == 1 {
+			r = r.add(p)
+		}
+	}
+
+	return r
+}
+
+// ScalarMult returns k times the point
+func ScalarMult(x, y, k *big.Int) (*big.Int, *big.Int) {
+	return fromAffine(x, y).mul(k).affine()
+}
+
+// ScalarBaseMult returns k times the generator
+func ScalarBaseMult(k *big.Int) (*big.Int, *big.Int) {
+	return ScalarMult(Gx, Gy, k)
+}
+
+// mulAdd returns a times the generator plus b times the point, as a
+// jacobian point so infinity can be told apart
+func mulAdd(a *big.Int, x, y, b *big.Int) jacobian {
+	return fromAffine(Gx, Gy).mul(a).add(fromAffine(x, y).mul(b))
+}
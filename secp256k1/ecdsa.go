@@ -0,0 +1,79 @@
+package secp256k1
+
+import (
+	"io"
+	"math/big"
+)
+
+// SignatureLen the length of ECDSA and Schnorr signatures: two 32-byte numbers
+const SignatureLen = 64
+
+// hashToInt takes the leftmost 256 bits of a hash
+func hashToInt(hash []byte) *big.Int {
+	if len(hash) > 32 {
+		hash = hash[:32]
+	}
+
+	return new(big.Int).SetBytes(hash)
+}
+
+// SignECDSA signs a hash, the signature is r || s with s in its low form
+func (k *PrivateKey) SignECDSA(rand io.Reader, hash []byte) ([]byte, error) {
+	e := hashToInt(hash)
+
+	for {
+		nonce, err := randScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+
+		rx, _ := ScalarBaseMult(nonce)
+		r := new(big.Int).Mod(rx, N)
+		if r.Sign() == 0 {
+			continue
+		}
+
+		// s = (e + r d) / nonce
+		s := new(big.Int).Mul(r, k.D)
+		s.Add(s, e)
+		s.Mul(s, new(big.Int).ModInverse(nonce, N))
+		s.Mod(s, N)
+		if s.Sign() == 0 {
+			continue
+		}
+		if s.Cmp(halfN) > 0 {
+			s.Sub(N, s)
+		}
+
+		sig := make([]byte, SignatureLen)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+
+		return sig, nil
+	}
+}
+
+// VerifyECDSA reports whether sig is a low-S signature of hash by the key
+func VerifyECDSA(pub *PublicKey, hash, sig []byte) bool {
+	if len(sig) != SignatureLen || !IsOnCurve(pub.X, pub.Y) {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Sign() == 0 || r.Cmp(N) >= 0 || s.Sign() == 0 || s.Cmp(halfN) > 0 {
+		return false
+	}
+
+	w := new(big.Int).ModInverse(s, N)
+	u1 := new(big.Int).Mul(hashToInt(hash), w)
+	u1.Mod(u1, N)
+	u2 := new(big.Int).Mul(r, w)
+	u2.Mod(u2, N)
+
+	x, _ := mulAdd(u1, pub.X, pub.Y, u2).affine()
+	if x == nil {
+		return false
+	}
+
+	return x.Mod(x, N).Cmp(r) == 0
+}
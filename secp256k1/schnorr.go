@@ -0,0 +1,98 @@
+package secp256k1
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// taggedHash hashes data under a tag, as BIP340 does to keep the hashes
+// of different uses apart
+func taggedHash(tag string, data ...[]byte) []byte {
+	tagHash := sha256.Sum256([]byte(tag))
+
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, d := range data {
+		h.Write(d)
+	}
+
+	return h.Sum(nil)
+}
+
+// SignSchnorr signs a message as BIP340 specifies, aux is 32 bytes of
+// fresh randomness mixed into the nonce, or nil
+func (k *PrivateKey) SignSchnorr(msg, aux []byte) ([]byte, error) {
+	if aux == nil {
+		aux = make([]byte, 32)
+	}
+
+	// the key used is the one of the public key with an even Y
+	d := new(big.Int).Set(k.D)
+	if k.Y.Bit(0) == 1 {
+		d.Sub(N, d)
+	}
+	pk := k.SerializeXOnly()
+
+	t := d.FillBytes(make([]byte, 32))
+	auxHash := taggedHash("BIP0340/aux", aux)
+	for i := range t {
+		t[i] ^= auxHash[i]
+	}
+
+	nonce := new(big.Int).SetBytes(taggedHash("BIP0340/nonce", t, pk, msg))
+	nonce.Mod(nonce, N)
+	if nonce.Sign() == 0 {
+		return nil, errors.New("secp256k1: zero nonce")
+	}
+	rx, ry := ScalarBaseMult(nonce)
+	if ry.Bit(0) == 1 {
+		nonce.Sub(N, nonce)
+	}
+	r := rx.FillBytes(make([]byte, 32))
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", r, pk, msg))
+	e.Mod(e, N)
+
+	// s = nonce + e d
+	s := e.Mul(e, d)
+	s.Add(s, nonce).Mod(s, N)
+
+	sig := append(r, s.FillBytes(make([]byte, 32))...)
+	if !VerifySchnorr(&k.PublicKey, msg, sig) {
+		return nil, errors.New("secp256k1: produced an invalid signature")
+	}
+
+	return sig, nil
+}
+
+// VerifySchnorr reports whether sig is a BIP340 signature of the message
+// by the key, only the X coordinate of the key is used
+func VerifySchnorr(pub *PublicKey, msg, sig []byte) bool {
+	if len(sig) != SignatureLen {
+		return false
+	}
+	pk := pub.SerializeXOnly()
+	px, py, ok := liftX(pk)
+	if !ok {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(P) >= 0 || s.Cmp(N) >= 0 {
+		return false
+	}
+
+	e := new(big.Int).SetBytes(taggedHash("BIP0340/challenge", sig[:32], pk, msg))
+	e.Mod(e, N)
+
+	// R = s G - e P
+	x, y := mulAdd(s, px, py, e.Sub(N, e)).affine()
+	if x == nil || y.Bit(0) == 1 {
+		return false
+	}
+
+	return x.Cmp(r) == 0
+}
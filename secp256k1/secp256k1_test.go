@@ -0,0 +1,221 @@
+package secp256k1
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	d, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestScalarBaseMult(t *testing.T) {
+	// 2G and N-1 times G, which is -G
+	x, y := ScalarBaseMult(big.NewInt(2))
+	if got := hex.EncodeToString(x.Bytes()); got != "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5" {
+		t.Errorf("2G x = %s", got)
+	}
+	if !IsOnCurve(x, y) {
+		t.Error("2G is not on the curve")
+	}
+
+	x, y = ScalarBaseMult(new(big.Int).Sub(N, big.NewInt(1)))
+	if x.Cmp(Gx) != 0 || new(big.Int).Add(y, Gy).Cmp(P) != 0 {
+		t.Error("(N-1)G is not -G")
+	}
+	if x, _ := fromAffine(Gx, Gy).mul(N).affine(); x != nil {
+		t.Error("NG is not the point at infinity")
+	}
+}
+
+func TestPubKeyEncoding(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		k, err := GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pub, err := ParsePubKey(k.SerializeCompressed())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pub.X.Cmp(k.X) != 0 || pub.Y.Cmp(k.Y) != 0 {
+			t.Fatal("compressed key parsed to another point")
+		}
+
+		xOnly, err := ParseXOnlyPubKey(k.SerializeXOnly())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xOnly.X.Cmp(k.X) != 0 || xOnly.Y.Bit(0) != 0 {
+			t.Fatal("x-only key parsed to a point with an odd Y")
+		}
+
+		same, err := NewPrivateKey(k.Bytes())
+		if err != nil || same.D.Cmp(k.D) != 0 {
+			t.Fatalf("private key round trip: %v", err)
+		}
+	}
+
+	bad := [][]byte{nil, make([]byte, 33), append([]byte{0x04}, make([]byte, 32)...)}
+	for _, d := range bad {
+		if _, err := ParsePubKey(d); err == nil {
+			t.Errorf("parsed %x", d)
+		}
+	}
+	if _, err := NewPrivateKey(N.Bytes()); err == nil {
+		t.Error("accepted N as a private key")
+	}
+}
+
+func TestECDSA(t *testing.T) {
+	k, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte("data"))
+
+	for i := 0; i < 10; i++ {
+		sig, err := k.SignECDSA(rand.Reader, hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !VerifyECDSA(&k.PublicKey, hash[:], sig) {
+			t.Fatal("signature does not verify")
+		}
+
+		other := sha256.Sum256([]byte("other"))
+		if VerifyECDSA(&k.PublicKey, other[:], sig) {
+			t.Fatal("signature verifies another hash")
+		}
+
+		s := new(big.Int).SetBytes(sig[32:])
+		high := append([]byte{}, sig...)
+		new(big.Int).Sub(N, s).FillBytes(high[32:])
+		if VerifyECDSA(&k.PublicKey, hash[:], high) {
+			t.Fatal("high-S signature verifies")
+		}
+	}
+}
+
+// BIP340 test vectors: secret key, public key, aux rand, message, signature, valid
+var schnorrVectors = []struct {
+	sk, pk, aux, msg, sig string
+	valid                 bool
+}{
+	{
+		"0000000000000000000000000000000000000000000000000000000000000003",
+		"F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA821525F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		true,
+	},
+	{
+		"B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+		"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE33418906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		true,
+	},
+	{
+		"C90FDAA22168C234C4C6628B80DC1CD129024E088A67CC74020BBEA63B14E5C9",
+		"DD308AFEC5777E13121FA72B9CC1B7CC0139715309B086C960E18FD969774EB8",
+		"C87AA53824B4D7AE2EB035A2B5BBBCCC080E76CDC6D1692C4B0B62D798E6D906",
+		"7E2D58D8B3BCDF1ABADEC7829054F90DDA9805AAB56C77333024B9D0A508B75C",
+		"5831AAEED7B44BB74E5EAB94BA9D4294C49BCF2A60728D8B4C200F50DD313C1BAB745879A5AD954A72C45A91C3A51D3C7ADEA98D82F8481E0E1E03674A6F3FB7",
+		true,
+	},
+	{
+		"",
+		"D69C3509BB99E412E68B0FE8544E72837DFA30746D8BE2AA65975F29D22DC7B9",
+		"",
+		"4DF3C3F68FCC83B27E9D42C90431A72499F17875C81A599B566C9889B9696703",
+		"00000000000000000000003B78CE563F89A0ED9414F5AA28AD0D96D6795F9C6376AFB1548AF603B3EB45C9F8207DEE1060CB71C04E80F593060B07D28308D7F4",
+		true,
+	},
+	{
+		// the public key is not on the curve
+		"",
+		"EEFDEA4CDB677750A420FEE807EACF21EB9898AE79B9768766E4FAA04A2D4A34",
+		"",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"6CFF5C3BA86C69EA4B7376F31A9BCB4F74C1976089B2D9963DA2E5543E17776969E89B4C5564D00349106B8497785DD7D1D713A8AE82B32FA79D5F7FC407D39B",
+		false,
+	},
+	{
+		// R has an odd Y
+		"",
+		"DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+		"",
+		"243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+		"FFF97BD5755EEEA420453A14355235D382F6472F8568A18B2F057A14602975563CC27944640AC607CD107AE10923D9EF7A73C643E166BE5EBEAFA34B1AC553E2",
+		false,
+	},
+}
+
+func TestSchnorrVectors(t *testing.T) {
+	for i, v := range schnorrVectors {
+		msg := decodeHex(t, v.msg)
+		sig := decodeHex(t, v.sig)
+
+		if v.sk != "" {
+			k, err := NewPrivateKey(decodeHex(t, v.sk))
+			if err != nil {
+				t.Fatalf("vector %d: %v", i, err)
+			}
+			if got := hex.EncodeToString(k.SerializeXOnly()); got != strings.ToLower(v.pk) {
+				t.Errorf("vector %d: public key %s", i, got)
+			}
+			got, err := k.SignSchnorr(msg, decodeHex(t, v.aux))
+			if err != nil {
+				t.Fatalf("vector %d: %v", i, err)
+			}
+			if !bytes.Equal(got, sig) {
+				t.Errorf("vector %d: signature %X", i, got)
+			}
+		}
+
+		pub, err := ParseXOnlyPubKey(decodeHex(t, v.pk))
+		if err != nil {
+			if v.valid {
+				t.Errorf("vector %d: %v", i, err)
+			}
+			continue
+		}
+		if VerifySchnorr(pub, msg, sig) != v.valid {
+			t.Errorf("vector %d: verify = %v, want %v", i, !v.valid, v.valid)
+		}
+	}
+}
+
+func TestSchnorr(t *testing.T) {
+	k, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := sha256.Sum256([]byte("data"))
+
+	sig, err := k.SignSchnorr(msg[:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !VerifySchnorr(&k.PublicKey, msg[:], sig) {
+		t.Fatal("signature does not verify")
+	}
+	sig[63] ^= 1
+	if VerifySchnorr(&k.PublicKey, msg[:], sig) {
+		t.Fatal("altered signature verifies")
+	}
+}
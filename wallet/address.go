@@ -0,0 +1,178 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+const addressChecksumLen = 4
+
+// pubKeyHashLen the length of the RIPEMD-160 hash an address pays to
+const pubKeyHashLen = 20
+
+// ErrInvalidAddress the address is malformed or has a bad checksum,
+// every error of ParseAddress is one
+var ErrInvalidAddress = errors.New("invalid address")
+
+// addressError a reason ParseAddress rejects an address
+type addressError string
+
+func (e addressError) Error() string {
+	return ErrInvalidAddress.Error() + ": " + string(e)
+}
+
+func (e addressError) Is(target error) bool {
+	return target == ErrInvalidAddress
+}
+
+var (
+	// ErrAddressCharacter the address has a character out of its alphabet,
+	// or mixes cases in Bech32
+	ErrAddressCharacter error = addressError("bad character")
+	// ErrAddressLength the address does not hold a version, a public key
+	// hash and a checksum
+	ErrAddressLength error = addressError("wrong length")
+	// ErrAddressVersion the version byte or key type of the address is
+	// not one of a known network, or not one of the expected network
+	ErrAddressVersion error = addressError("unknown version")
+	// ErrAddressChecksum the checksum does not match, the address was
+	// mistyped
+	ErrAddressChecksum error = addressError("bad checksum")
+)
+
+// Address an address: the key type and public key hash it pays to,
+// and how it is encoded
+type Address struct {
+	KeyType    KeyType
+	PubKeyHash []byte
+	Format     AddressFormat
+	// Version the version byte of a Base58Check address
+	Version byte
+	// HRP the human-readable part of a Bech32 address
+	HRP string
+}
+
+// NewAddress returns the address of a network paying to a public key hash
+func NewAddress(t KeyType, pubKeyHash []byte, format AddressFormat, params *chaincfg.Params) Address {
+	version, _ := addressID(t, params)
+
+	return Address{t, pubKeyHash, format, version, params.Bech32HRP}
+}
+
+// ParseAddress parses a Base58Check or Bech32 address of any known network,
+// IsForNet tells which ones it belongs to
+func ParseAddress(address string) (Address, error) {
+	lower := strings.ToLower(address)
+	for _, params := range chaincfg.Networks {
+		if strings.HasPrefix(lower, params.Bech32HRP+"1") {
+			return decodeBech32Address(address)
+		}
+	}
+
+	payload, err := Base58Decode([]byte(address))
+	if err != nil {
+		return Address{}, fmt.Errorf("%w: %v", ErrAddressCharacter, err)
+	}
+	if len(payload) != 1+pubKeyHashLen+addressChecksumLen {
+		return Address{}, fmt.Errorf("%w: %d bytes", ErrAddressLength, len(payload))
+	}
+
+	versionedPayload := payload[:len(payload)-addressChecksumLen]
+	if !bytes.Equal(payload[len(versionedPayload):], checkSum(versionedPayload)) {
+		return Address{}, ErrAddressChecksum
+	}
+
+	version := payload[0]
+	for _, params := range chaincfg.Networks {
+		for _, t := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr, KeyScriptHash} {
+			if id, _ := addressID(t, params); id == version {
+				return Address{t, versionedPayload[1:], AddressBase58, version, ""}, nil
+			}
+		}
+	}
+
+	return Address{}, fmt.Errorf("%w: 0x%02x", ErrAddressVersion, version)
+}
+
+// IsForNet reports whether the address belongs to a network. Networks
+// sharing version bytes share Base58Check addresses.
+func (a Address) IsForNet(params *chaincfg.Params) bool {
+	if a.Format == AddressBech32 {
+		return a.HRP == params.Bech32HRP
+	}
+	version, ok := addressID(a.KeyType, params)
+
+	return ok && version == a.Version
+}
+
+// String encodes the address in its format
+func (a Address) String() string {
+	if a.Format == AddressBech32 {
+		// the human-readable part of the presets is valid
+		address, _ := bech32Address(a.HRP, a.KeyType, a.PubKeyHash)
+		return address
+	}
+
+	versionedPayload := append([]byte{a.Version}, a.PubKeyHash...)
+	payload := append(versionedPayload, checkSum(versionedPayload)...)
+
+	return string(Base58Encode(payload))
+}
+
+// addressID returns the version byte of the addresses of a key type
+func addressID(t KeyType, params *chaincfg.Params) (byte, bool) {
+	switch t {
+	case KeyP256:
+		return params.PubKeyHashAddrID, true
+	case KeySecp256k1:
+		return params.Secp256k1PubKeyHashAddrID, true
+	case KeySchnorr:
+		return params.SchnorrPubKeyHashAddrID, true
+	case KeyScriptHash:
+		return params.ScriptHashAddrID, true
+	}
+
+	return 0, false
+}
+
+// DecodeAddress returns the key type and public key hash an address of
+// the network pays to, the address is either in Base58Check or Bech32
+func DecodeAddress(address string, params *chaincfg.Params) (KeyType, []byte, error) {
+	a, err := ParseAddress(address)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !a.IsForNet(params) {
+		return 0, nil, fmt.Errorf("%w: not a %s address", ErrAddressVersion, params.Name)
+	}
+
+	return a.KeyType, a.PubKeyHash, nil
+}
+
+// ValidateAddress check if address if valid
+func ValidateAddress(address string, params *chaincfg.Params) bool {
+	_, _, err := DecodeAddress(address, params)
+	return err == nil
+}
+
+// PubKeyHashFromAddress extracts the public key hash an address pays to
+func PubKeyHashFromAddress(address string, params *chaincfg.Params) ([]byte, error) {
+	_, pubKeyHash, err := DecodeAddress(address, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return pubKeyHash, nil
+}
+
+func checkSum(payload []byte) []byte {
+	firstSHA := sha256.Sum256(payload)
+	secondSHA := sha256.Sum256(firstSHA[:])
+
+	return secondSHA[:addressChecksumLen]
+}
@@ -0,0 +1,150 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+// the Base58 vectors of Bitcoin Core, base58_encode_decode.json
+var base58Vectors = []struct {
+	hex, b58 string
+}{
+	{"", ""},
+	{"61", "2g"},
+	{"626262", "a3gV"},
+	{"636363", "aPEr"},
+	{"73696d706c792061206c6f6e6720737472696e67", "2cFupjhnEsSn59qHXstmK2ffpLv2"},
+	{"00eb15231dfceb60925886b67d065299925915aeb172c06647", "1NS17iag9jJgTHD1VXjvLCEnZuQ3rJDE9L"},
+	{"516b6fcd0f", "ABnLTmg"},
+	{"bf4f89001e670274dd", "3SEo3LWLoPntC"},
+	{"572e4794", "3EFU7m"},
+	{"ecac89cad93923c02321", "EJDM8drfXA6uyA"},
+	{"10c8511e", "Rt5zm"},
+	{"00000000000000000000", "1111111111"},
+	{"000111d38e5fc9071ffcd20b4a763cc9ae4f252bb4e48fd66a835e252ada93ff480d6dd43dc62a641155a5", "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"},
+}
+
+func TestBase58Vectors(t *testing.T) {
+	for _, v := range base58Vectors {
+		d, err := hex.DecodeString(v.hex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(Base58Encode(d)); got != v.b58 {
+			t.Errorf("encode %s = %s, want %s", v.hex, got, v.b58)
+		}
+		got, err := Base58Decode([]byte(v.b58))
+		if err != nil {
+			t.Errorf("decode %s: %v", v.b58, err)
+			continue
+		}
+		if !bytes.Equal(got, d) {
+			t.Errorf("decode %s = %x, want %s", v.b58, got, v.hex)
+		}
+	}
+
+	for _, s := range []string{"0", "O", "I", "l", "3SEo3LWLoPntC0", "1 1", "é"} {
+		if _, err := Base58Decode([]byte(s)); !errors.Is(err, ErrInvalidBase58) {
+			t.Errorf("decode %q: %v", s, err)
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, v := range []struct {
+		address    string
+		keyType    KeyType
+		pubKeyHash string
+		params     *chaincfg.Params
+	}{
+		// the address of the Bitcoin genesis block, whose version byte
+		// mainnet P-256 addresses share
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", KeyP256, "62e907b15cbf27d5425399ebf6f0fb50ebb88f18", &chaincfg.MainNetParams},
+		// a Bitcoin pay-to-script-hash address
+		{"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", KeyScriptHash, "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb", &chaincfg.MainNetParams},
+	} {
+		a, err := ParseAddress(v.address)
+		if err != nil {
+			t.Errorf("%s: %v", v.address, err)
+			continue
+		}
+		if a.KeyType != v.keyType || hex.EncodeToString(a.PubKeyHash) != v.pubKeyHash || !a.IsForNet(v.params) {
+			t.Errorf("%s: parsed %+v", v.address, a)
+		}
+		if a.String() != v.address {
+			t.Errorf("%s: encoded back to %s", v.address, a)
+		}
+	}
+
+	valid := "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"
+	for _, v := range []struct {
+		address string
+		err     error
+	}{
+		{"", ErrAddressLength},
+		{"1", ErrAddressLength},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7Di", ErrAddressLength},
+		{valid + "a", ErrAddressLength},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfN0", ErrAddressCharacter},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", ErrAddressChecksum},
+		{"1B1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", ErrAddressChecksum},
+		{"bcg1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", ErrAddressChecksum},
+		{"bcg1Qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", ErrAddressCharacter},
+		{"bcg1bw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", ErrAddressCharacter},
+	} {
+		_, err := ParseAddress(v.address)
+		if !errors.Is(err, v.err) || !errors.Is(err, ErrInvalidAddress) {
+			t.Errorf("%q: %v, want %v", v.address, err, v.err)
+		}
+	}
+
+	// addresses of other lengths and key types with a valid checksum
+	params := &chaincfg.MainNetParams
+	for _, v := range []struct {
+		a   Address
+		err error
+	}{
+		{Address{KeyP256, make([]byte, 19), AddressBase58, params.PubKeyHashAddrID, ""}, ErrAddressLength},
+		{Address{KeyP256, make([]byte, 32), AddressBech32, 0, params.Bech32HRP}, ErrAddressLength},
+		{Address{KeyType(7), make([]byte, 20), AddressBech32, 0, params.Bech32HRP}, ErrAddressVersion},
+		{Address{KeyP256, make([]byte, 20), AddressBase58, 0x99, ""}, ErrAddressVersion},
+	} {
+		if _, err := ParseAddress(v.a.String()); !errors.Is(err, v.err) {
+			t.Errorf("%s: %v, want %v", v.a, err, v.err)
+		}
+	}
+}
+
+func TestAddressNetworks(t *testing.T) {
+	pubKeyHash := HashPublicKey([]byte("key"))
+
+	for _, params := range chaincfg.Networks {
+		for _, keyType := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr, KeyScriptHash} {
+			for _, format := range []AddressFormat{AddressBase58, AddressBech32} {
+				address := NewAddress(keyType, pubKeyHash, format, params).String()
+				a, err := ParseAddress(address)
+				if err != nil {
+					t.Fatalf("%s: %v", address, err)
+				}
+				if a.KeyType != keyType || a.Format != format || !bytes.Equal(a.PubKeyHash, pubKeyHash) {
+					t.Errorf("%s: parsed %+v", address, a)
+				}
+				if !a.IsForNet(params) {
+					t.Errorf("%s: not a %s address", address, params.Name)
+				}
+				if _, _, err := DecodeAddress(address, params); err != nil {
+					t.Errorf("%s: %v", address, err)
+				}
+			}
+		}
+	}
+
+	mainnet := NewAddress(KeyP256, pubKeyHash, AddressBase58, &chaincfg.MainNetParams).String()
+	if _, _, err := DecodeAddress(mainnet, &chaincfg.RegTestParams); !errors.Is(err, ErrAddressVersion) {
+		t.Errorf("mainnet address on regtest: %v", err)
+	}
+}
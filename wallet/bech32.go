@@ -0,0 +1,218 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32MaxLen the longest string BIP173 allows
+const bech32MaxLen = 90
+
+// Bech32Variant the checksum constant of a Bech32 string: BIP173 Bech32,
+// or BIP350 Bech32m
+type Bech32Variant uint32
+
+const (
+	// Bech32 the variant of BIP173
+	Bech32 Bech32Variant = 1
+	// Bech32m the variant of BIP350
+	Bech32m Bech32Variant = 0x2bc830a3
+)
+
+// ErrInvalidBech32 a string is not valid Bech32 or Bech32m
+var ErrInvalidBech32 = errors.New("invalid bech32 string")
+
+// bech32Error an error of decoding Bech32, it is ErrInvalidBech32 and
+// wraps the address error of the same kind
+type bech32Error struct {
+	kind   error
+	detail string
+}
+
+func (e bech32Error) Error() string {
+	return ErrInvalidBech32.Error() + ": " + e.detail
+}
+
+func (e bech32Error) Is(target error) bool {
+	return target == ErrInvalidBech32
+}
+
+func (e bech32Error) Unwrap() error {
+	return e.kind
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	d := make([]byte, 0, 2*len(hrp)+1)
+	for i := 0; i < len(hrp); i++ {
+		d = append(d, hrp[i]>>5)
+	}
+	d = append(d, 0)
+	for i := 0; i < len(hrp); i++ {
+		d = append(d, hrp[i]&31)
+	}
+
+	return d
+}
+
+// Bech32Encode encodes the 5-bit values of data under the human-readable
+// part, in lower case
+func Bech32Encode(hrp string, data []byte, variant Bech32Variant) (string, error) {
+	hrp = strings.ToLower(hrp)
+	if len(hrp) == 0 || len(hrp)+1+len(data)+6 > bech32MaxLen {
+		return "", fmt.Errorf("%w: length", ErrInvalidBech32)
+	}
+
+	values := append(bech32HRPExpand(hrp), data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+	mod := bech32Polymod(values) ^ uint32(variant)
+
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range data {
+		if v >= 32 {
+			return "", fmt.Errorf("%w: value %d is not 5 bits", ErrInvalidBech32, v)
+		}
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(mod>>uint(5*(5-i)))&31])
+	}
+
+	return sb.String(), nil
+}
+
+// Bech32Decode returns the human-readable part, the 5-bit values and
+// the variant of a Bech32 or Bech32m string
+func Bech32Decode(s string) (string, []byte, Bech32Variant, error) {
+	if len(s) > bech32MaxLen {
+		return "", nil, 0, bech32Error{ErrAddressLength, fmt.Sprintf("longer than %d characters", bech32MaxLen)}
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, bech32Error{ErrAddressCharacter, "mixed case"}
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			return "", nil, 0, bech32Error{ErrAddressCharacter, fmt.Sprintf("character %q", s[i])}
+		}
+	}
+	s = strings.ToLower(s)
+
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, 0, bech32Error{ErrAddressLength, "no separator or checksum"}
+	}
+	hrp := s[:sep]
+
+	data := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, 0, bech32Error{ErrAddressCharacter, fmt.Sprintf("character %q", s[i])}
+		}
+		data = append(data, byte(v))
+	}
+
+	variant := Bech32Variant(bech32Polymod(append(bech32HRPExpand(hrp), data...)))
+	if variant != Bech32 && variant != Bech32m {
+		return "", nil, 0, bech32Error{ErrAddressChecksum, "bad checksum"}
+	}
+
+	return hrp, data[:len(data)-6], variant, nil
+}
+
+// convertBits regroups the bits of data from groups of from bits to
+// groups of to bits, padding the last one with zeros when pad is set
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	var acc, bits uint
+	maxv := uint(1)<<to - 1
+
+	var out []byte
+	for _, v := range data {
+		if uint(v)>>from != 0 {
+			return nil, bech32Error{ErrAddressCharacter, fmt.Sprintf("value %d is not %d bits", v, from)}
+		}
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, bech32Error{ErrAddressLength, "bad padding"}
+	}
+
+	return out, nil
+}
+
+// bech32Address encodes a Bech32 address: the key type takes the place of
+// the segwit version, P-256 keys use Bech32 and the others Bech32m, as
+// BIP350 does for versions above 0
+func bech32Address(hrp string, t KeyType, pubKeyHash []byte) (string, error) {
+	program, err := convertBits(pubKeyHash, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	variant := Bech32m
+	if t == KeyP256 {
+		variant = Bech32
+	}
+
+	return Bech32Encode(hrp, append([]byte{byte(t)}, program...), variant)
+}
+
+// decodeBech32Address parses a Bech32 address, of any human-readable part
+func decodeBech32Address(address string) (Address, error) {
+	hrp, data, variant, err := Bech32Decode(address)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(data) == 0 {
+		return Address{}, fmt.Errorf("%w: no key type", ErrAddressLength)
+	}
+
+	t := KeyType(data[0])
+	if _, ok := keyTypeNames[t]; !ok {
+		return Address{}, fmt.Errorf("%w: key type %d", ErrAddressVersion, data[0])
+	}
+	if (t == KeyP256) != (variant == Bech32) {
+		return Address{}, fmt.Errorf("%w: wrong variant for a %s key", ErrAddressChecksum, t)
+	}
+
+	pubKeyHash, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		return Address{}, err
+	}
+	if len(pubKeyHash) != pubKeyHashLen {
+		return Address{}, fmt.Errorf("%w: public key hash of %d bytes", ErrAddressLength, len(pubKeyHash))
+	}
+
+	return Address{KeyType: t, PubKeyHash: pubKeyHash, Format: AddressBech32, HRP: hrp}, nil
+}
@@ -0,0 +1,114 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+// valid strings of BIP173 and BIP350
+var bech32Vectors = []struct {
+	s       string
+	variant Bech32Variant
+}{
+	{"A12UEL5L", Bech32},
+	{"a12uel5l", Bech32},
+	{"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs", Bech32},
+	{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32},
+	{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32},
+	{"?1ezyfcl", Bech32},
+	{"A1LQFN3A", Bech32m},
+	{"a1lqfn3a", Bech32m},
+	{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m},
+	{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m},
+	{"?1v759aa", Bech32m},
+}
+
+func TestBech32Vectors(t *testing.T) {
+	for _, v := range bech32Vectors {
+		hrp, data, variant, err := Bech32Decode(v.s)
+		if err != nil {
+			t.Errorf("%s: %v", v.s, err)
+			continue
+		}
+		if variant != v.variant {
+			t.Errorf("%s: variant %x, want %x", v.s, variant, v.variant)
+		}
+		encoded, err := Bech32Encode(hrp, data, variant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if encoded != strings.ToLower(v.s) {
+			t.Errorf("%s: encoded back to %s", v.s, encoded)
+		}
+	}
+
+	for _, s := range []string{
+		"A12uEL5L",     // mixed case
+		"pzry9x0s0muk", // no separator
+		"1pzry9x0s0muk",
+		"x1b4n0q5v", // invalid character
+		"li1dgmt3",  // checksum too short
+		"A1G7SGD8",  // checksum computed with an upper case prefix
+		"a12uel5m",  // bad checksum
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx",
+	} {
+		if _, _, _, err := Bech32Decode(s); !errors.Is(err, ErrInvalidBech32) {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+}
+
+func TestSegwitProgram(t *testing.T) {
+	// the pay-to-witness-public-key-hash address of BIP173
+	_, data, variant, err := Bech32Decode("BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := convertBits(data[1:], 5, 8, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variant != Bech32 || data[0] != 0 || hex.EncodeToString(program) != "751e76e8199196d454941c45d1b3a323f1433bd6" {
+		t.Errorf("decoded version %d program %x", data[0], program)
+	}
+}
+
+func TestBech32Address(t *testing.T) {
+	params := &chaincfg.RegTestParams
+
+	for _, keyType := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr} {
+		w, err := NewWalletWithKeyType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Format = AddressBech32
+		address := string(w.Address(params))
+		if !strings.HasPrefix(address, params.Bech32HRP+"1") {
+			t.Fatalf("address %s", address)
+		}
+
+		// both cases decode, the address of another network does not
+		for _, s := range []string{address, strings.ToUpper(address)} {
+			gotType, pubKeyHash, err := DecodeAddress(s, params)
+			if err != nil {
+				t.Fatalf("%s: %v", s, err)
+			}
+			if gotType != keyType || hex.EncodeToString(pubKeyHash) != hex.EncodeToString(HashPublicKey(w.PublicKey)) {
+				t.Errorf("%s: decoded a %s key hash %x", s, gotType, pubKeyHash)
+			}
+		}
+		if ValidateAddress(address, &chaincfg.MainNetParams) {
+			t.Errorf("regtest address %s valid on mainnet", address)
+		}
+
+		typo := []byte(address)
+		typo[len(typo)-1] = map[bool]byte{true: 'q', false: 'p'}[typo[len(typo)-1] != 'q']
+		if ValidateAddress(string(typo), params) {
+			t.Errorf("address with a typo %s is valid", typo)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/MikasaAkerman/blockchain-go/secp256k1"
+)
+
+// KeyType the curve and signature scheme of a key. Wallets record it and
+// the outputs paying them too, so their inputs are verified the same way.
+type KeyType byte
+
+const (
+	// KeyP256 ECDSA over NIST P-256, the type of the first wallets
+	KeyP256 KeyType = iota
+	// KeySecp256k1 ECDSA over secp256k1
+	KeySecp256k1
+	// KeySchnorr BIP340 Schnorr signatures over secp256k1
+	KeySchnorr
+	// KeyScriptHash not a key but a redeem script, the input spending an
+	// output paying its hash reveals it and the signatures it requires
+	KeyScriptHash
+)
+
+var keyTypeNames = map[KeyType]string{
+	KeyP256:       "p256",
+	KeySecp256k1:  "secp256k1",
+	KeySchnorr:    "schnorr",
+	KeyScriptHash: "scripthash",
+}
+
+func (t KeyType) String() string {
+	name, ok := keyTypeNames[t]
+	if !ok {
+		return fmt.Sprintf("KeyType(%d)", byte(t))
+	}
+
+	return name
+}
+
+// ParseKeyType returns the key type of a name String returns
+func ParseKeyType(name string) (KeyType, error) {
+	for t, n := range keyTypeNames {
+		if n == name {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown key type: %s", name)
+}
+
+// PubKeyLen the length of a compressed public key: a parity byte and X
+const PubKeyLen = 33
+
+// SignatureLen the length of a signature: r and s, 32 bytes each
+const SignatureLen = 64
+
+var (
+	// ErrInvalidPubKey a public key is malformed or not on the curve
+	ErrInvalidPubKey = errors.New("invalid public key")
+	// ErrInvalidSignature a signature is malformed, not in low-S form,
+	// or does not match the key and data
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+func curve() elliptic.Curve {
+	return elliptic.P256()
+}
+
+// SerializePubKey returns the compressed form of a P-256 public key
+func SerializePubKey(pub *ecdsa.PublicKey) []byte {
+	return elliptic.MarshalCompressed(pub.Curve, pub.X, pub.Y)
+}
+
+// ParsePubKey parses a compressed P-256 public key, or the X || Y
+// concatenation of version 0 wallets. Their coordinates were not padded,
+// so the split leaving a point on the curve is taken.
+func ParsePubKey(d []byte) (*ecdsa.PublicKey, error) {
+	c := curve()
+	size := (c.Params().BitSize + 7) / 8
+
+	if len(d) == PubKeyLen {
+		x, y := elliptic.UnmarshalCompressed(c, d)
+		if x == nil {
+			return nil, fmt.Errorf("%w: bad compressed key", ErrInvalidPubKey)
+		}
+		return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+	}
+	if len(d) <= PubKeyLen || len(d) > 2*size {
+		return nil, fmt.Errorf("%w: length %d", ErrInvalidPubKey, len(d))
+	}
+
+	for i := len(d) - size; i <= size; i++ {
+		x := new(big.Int).SetBytes(d[:i])
+		y := new(big.Int).SetBytes(d[i:])
+		if c.IsOnCurve(x, y) {
+			return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: not on the curve", ErrInvalidPubKey)
+}
+
+// legacyPubKey returns the public key as version 0 wallets stored it
+func legacyPubKey(pub *ecdsa.PublicKey) []byte {
+	return append(pub.X.Bytes(), pub.Y.Bytes()...)
+}
+
+// p256Key returns the P-256 private key of a scalar
+func p256Key(d []byte) *ecdsa.PrivateKey {
+	c := curve()
+	priv := &ecdsa.PrivateKey{D: new(big.Int).SetBytes(d)}
+	priv.PublicKey.Curve = c
+	priv.PublicKey.X, priv.PublicKey.Y = c.ScalarBaseMult(d)
+
+	return priv
+}
+
+// newKeyPair generates the private scalar and the public key of a key type
+func newKeyPair(t KeyType) ([]byte, []byte, error) {
+	switch t {
+	case KeyP256:
+		priv, err := ecdsa.GenerateKey(curve(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return priv.D.FillBytes(make([]byte, 32)), SerializePubKey(&priv.PublicKey), nil
+	case KeySecp256k1, KeySchnorr:
+		priv, err := secp256k1.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return priv.Bytes(), secp256k1PubKey(t, &priv.PublicKey), nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown key type: %d", byte(t))
+}
+
+func secp256k1PubKey(t KeyType, pub *secp256k1.PublicKey) []byte {
+	if t == KeySchnorr {
+		return pub.SerializeXOnly()
+	}
+
+	return pub.SerializeCompressed()
+}
+
+// publicKey derives the public key of a private scalar, P-256 keys are
+// returned as points since version 0 wallets may hold another form
+func publicKey(t KeyType, d []byte) (*ecdsa.PublicKey, []byte, error) {
+	switch t {
+	case KeyP256:
+		return &p256Key(d).PublicKey, nil, nil
+	case KeySecp256k1, KeySchnorr:
+		priv, err := secp256k1.NewPrivateKey(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, secp256k1PubKey(t, &priv.PublicKey), nil
+	}
+
+	return nil, nil, fmt.Errorf("unknown key type: %d", byte(t))
+}
+
+// sign signs a hash with the private scalar of a key type. ECDSA
+// signatures are r || s padded to 32 bytes each, with s in its low form:
+// of s and N-s, the smaller one is taken, so a signature cannot be
+// altered into another valid one.
+func sign(t KeyType, d, hash []byte) ([]byte, error) {
+	switch t {
+	case KeyP256:
+		priv := p256Key(d)
+		r, s, err := ecdsa.Sign(rand.Reader, priv, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		n := priv.Curve.Params().N
+		if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+			s.Sub(n, s)
+		}
+
+		sig := make([]byte, SignatureLen)
+		r.FillBytes(sig[:SignatureLen/2])
+		s.FillBytes(sig[SignatureLen/2:])
+
+		return sig, nil
+	case KeySecp256k1, KeySchnorr:
+		priv, err := secp256k1.NewPrivateKey(d)
+		if err != nil {
+			return nil, err
+		}
+		if t == KeySchnorr {
+			aux := make([]byte, 32)
+			_, err = rand.Read(aux)
+			if err != nil {
+				return nil, err
+			}
+			return priv.SignSchnorr(hash, aux)
+		}
+		return priv.SignECDSA(rand.Reader, hash)
+	}
+
+	return nil, fmt.Errorf("unknown key type: %d", byte(t))
+}
+
+// VerifySignature checks sig is a signature of hash by the public key of
+// the key type, it only accepts the strict encodings the wallets produce
+func VerifySignature(t KeyType, pubKey, hash, sig []byte) error {
+	switch t {
+	case KeyP256:
+		return verifyP256(pubKey, hash, sig)
+	case KeySecp256k1:
+		pub, err := secp256k1.ParsePubKey(pubKey)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPubKey, err)
+		}
+		if !secp256k1.VerifyECDSA(pub, hash, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case KeySchnorr:
+		pub, err := secp256k1.ParseXOnlyPubKey(pubKey)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPubKey, err)
+		}
+		if !secp256k1.VerifySchnorr(pub, hash, sig) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown key type %d", ErrInvalidSignature, byte(t))
+}
+
+func verifyP256(pubKey, hash, sig []byte) error {
+	pub, err := ParsePubKey(pubKey)
+	if err != nil {
+		return err
+	}
+
+	if len(sig) != SignatureLen {
+		return fmt.Errorf("%w: length %d", ErrInvalidSignature, len(sig))
+	}
+	n := pub.Curve.Params().N
+	r := new(big.Int).SetBytes(sig[:SignatureLen/2])
+	s := new(big.Int).SetBytes(sig[SignatureLen/2:])
+	if r.Sign() == 0 || r.Cmp(n) >= 0 || s.Sign() == 0 || s.Cmp(n) >= 0 {
+		return fmt.Errorf("%w: r or s out of range", ErrInvalidSignature)
+	}
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		return fmt.Errorf("%w: s is not in low form", ErrInvalidSignature)
+	}
+
+	if !ecdsa.Verify(pub, hash, r, s) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
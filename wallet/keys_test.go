@@ -0,0 +1,277 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+// legacyKey generates keys until one has a coordinate with a leading zero
+// byte, which version 0 wallets stored unpadded
+func legacyKey(t *testing.T) *ecdsa.PrivateKey {
+	for {
+		priv, err := ecdsa.GenerateKey(curve(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(legacyPubKey(&priv.PublicKey)) < 64 {
+			return priv
+		}
+	}
+}
+
+// baselineCurve the P-256 curve as the Go releases of the first wallet
+// files encoded it
+type baselineCurve struct {
+	*elliptic.CurveParams
+}
+
+func init() {
+	gob.RegisterName("crypto/elliptic.p256Curve", baselineCurve{})
+}
+
+// baselineWallet the layout of the wallets of the first files: a
+// crypto/ecdsa private key and the unpadded X || Y public key
+type baselineWallet struct {
+	PrivateKey struct {
+		PublicKey struct {
+			Curve elliptic.Curve
+			X, Y  *big.Int
+		}
+		D *big.Int
+	}
+	PublicKey []byte
+}
+
+func newBaselineWallet(priv *ecdsa.PrivateKey) *baselineWallet {
+	var w baselineWallet
+	w.PrivateKey.PublicKey.Curve = baselineCurve{curve().Params()}
+	w.PrivateKey.PublicKey.X, w.PrivateKey.PublicKey.Y = priv.X, priv.Y
+	w.PrivateKey.D = priv.D
+	w.PublicKey = legacyPubKey(&priv.PublicKey)
+
+	return &w
+}
+
+func TestParsePubKey(t *testing.T) {
+	priv := legacyKey(t)
+	pub := &priv.PublicKey
+
+	for name, d := range map[string][]byte{
+		"compressed": SerializePubKey(pub),
+		"legacy":     legacyPubKey(pub),
+	} {
+		got, err := ParsePubKey(d)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got.X.Cmp(pub.X) != 0 || got.Y.Cmp(pub.Y) != 0 {
+			t.Errorf("%s: parsed another key", name)
+		}
+	}
+
+	bad := SerializePubKey(pub)
+	bad[0] = 0x05
+	for _, d := range [][]byte{nil, bad, make([]byte, 64), make([]byte, 65)} {
+		if _, err := ParsePubKey(d); !errors.Is(err, ErrInvalidPubKey) {
+			t.Errorf("parse %x: %v", d, err)
+		}
+	}
+}
+
+func TestSignatureEncoding(t *testing.T) {
+	priv := legacyKey(t)
+	pubKey := SerializePubKey(&priv.PublicKey)
+	hash := sha256.Sum256([]byte("data"))
+
+	for i := 0; i < 20; i++ {
+		sig, err := sign(KeyP256, priv.D.Bytes(), hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sig) != SignatureLen {
+			t.Fatalf("signature length %d", len(sig))
+		}
+		if err := VerifySignature(KeyP256, pubKey, hash[:], sig); err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySignature(KeyP256, legacyPubKey(&priv.PublicKey), hash[:], sig); err != nil {
+			t.Fatalf("legacy key: %v", err)
+		}
+
+		// the high-S twin is valid ECDSA but not strictly encoded
+		n := curve().Params().N
+		s := new(big.Int).SetBytes(sig[32:])
+		high := append([]byte{}, sig...)
+		new(big.Int).Sub(n, s).FillBytes(high[32:])
+		if err := VerifySignature(KeyP256, pubKey, hash[:], high); !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("high-S signature: %v", err)
+		}
+	}
+
+	sig, err := sign(KeyP256, priv.D.Bytes(), hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range [][]byte{sig[:63], append(sig, 0), make([]byte, 64)} {
+		if err := VerifySignature(KeyP256, pubKey, hash[:], bad); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("verify %x: %v", bad, err)
+		}
+	}
+}
+
+func TestMigrateWallets(t *testing.T) {
+	params := &chaincfg.RegTestParams
+	file := filepath.Join(t.TempDir(), "wallet.dat")
+
+	// a file of the first wallets
+	priv := legacyKey(t)
+	legacy := &Wallet{KeyP256, priv.D.Bytes(), legacyPubKey(&priv.PublicKey), AddressBase58}
+	address := string(legacy.Address(params))
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(struct{ Wallets map[string]*baselineWallet }{map[string]*baselineWallet{address: newBaselineWallet(priv)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := NewWallets(file, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := ws.Wallet(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(w.Address(params)) != address {
+		t.Errorf("migrated wallet address %s, want %s", w.Address(params), address)
+	}
+	if new(big.Int).SetBytes(w.PrivateKey).Cmp(priv.D) != 0 {
+		t.Error("migrated wallet has another private key")
+	}
+
+	created, err := ws.CreateWallet(KeySchnorr, AddressBech32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws.LockOutput("00ff:0")
+	ws.LockOutput("00ff:1")
+	ws.UnlockOutput("00ff:0")
+	if err := ws.SaveToFile(); err != nil {
+		t.Fatal(err)
+	}
+	ws, err = NewWallets(file, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err = ws.Wallet(created)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.KeyType != KeySchnorr || len(w.PublicKey) != 32 {
+		t.Errorf("new wallet has a %s key of %d bytes", w.KeyType, len(w.PublicKey))
+	}
+	if _, err := ws.Wallet(address); err != nil {
+		t.Errorf("version 0 wallet after saving: %v", err)
+	}
+	if locked := ws.LockedOutputs(); len(locked) != 1 || !locked["00ff:1"] {
+		t.Errorf("locked outputs %v", locked)
+	}
+
+	// a version 0 file of wallets encoding themselves, which had no version
+	file = filepath.Join(t.TempDir(), "wallet.dat")
+	buf.Reset()
+	err = gob.NewEncoder(&buf).Encode(struct{ Wallets map[string]*Wallet }{map[string]*Wallet{address: legacy}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ws, err = NewWallets(file, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err = ws.Wallet(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(w.PublicKey, legacy.PublicKey) || ws.Version != walletFileVersion {
+		t.Errorf("wallet %x of a version %d file", w.PublicKey, ws.Version)
+	}
+}
+
+func TestKeyTypes(t *testing.T) {
+	params := &chaincfg.RegTestParams
+	hash := sha256.Sum256([]byte("data"))
+
+	for _, keyType := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr} {
+		w, err := NewWalletWithKeyType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.checkKey(); err != nil {
+			t.Errorf("%s: %v", keyType, err)
+		}
+
+		sig, err := w.Sign(hash[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := VerifySignature(keyType, w.PublicKey, hash[:], sig); err != nil {
+			t.Errorf("%s: %v", keyType, err)
+		}
+		other := sha256.Sum256([]byte("other"))
+		if err := VerifySignature(keyType, w.PublicKey, other[:], sig); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: signature of another hash: %v", keyType, err)
+		}
+
+		// the address tells the key type
+		gotType, pubKeyHash, err := DecodeAddress(string(w.Address(params)), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gotType != keyType || !bytes.Equal(pubKeyHash, HashPublicKey(w.PublicKey)) {
+			t.Errorf("%s: address decodes to a %s key hash %x", keyType, gotType, pubKeyHash)
+		}
+
+		// a wallet survives its persisted form
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(w); err != nil {
+			t.Fatal(err)
+		}
+		var decoded Wallet
+		if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.KeyType != keyType || decoded.checkKey() != nil {
+			t.Errorf("%s: decoded wallet has a %s key", keyType, decoded.KeyType)
+		}
+	}
+
+	// a secp256k1 signature is not a Schnorr one, even by the same key
+	w, err := NewWalletWithKeyType(KeySecp256k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := w.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignature(KeySchnorr, w.PublicKey[1:], hash[:], sig); err == nil {
+		t.Error("ECDSA signature verified as a Schnorr one")
+	}
+}
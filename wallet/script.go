@@ -0,0 +1,190 @@
+package wallet
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+// MaxScriptKeys the most keys a redeem script holds
+const MaxScriptKeys = 16
+
+// ErrInvalidScript a redeem script or the signatures satisfying it
+// are malformed
+var ErrInvalidScript = errors.New("invalid redeem script")
+
+// ScriptKey a key of a redeem script
+type ScriptKey struct {
+	KeyType KeyType
+	PubKey  []byte
+}
+
+// RedeemScript the condition of a pay-to-script-hash output: it is spent
+// with signatures of Required of the keys. The payer only knows the hash,
+// the input spending the output reveals the script.
+type RedeemScript struct {
+	Required int
+	Keys     []ScriptKey
+}
+
+// NewMultiSigScript returns the script requiring signatures of required
+// of the keys
+func NewMultiSigScript(required int, keys []ScriptKey) (*RedeemScript, error) {
+	s := &RedeemScript{required, keys}
+
+	err := s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *RedeemScript) check() error {
+	if len(s.Keys) == 0 || len(s.Keys) > MaxScriptKeys {
+		return fmt.Errorf("%w: %d keys", ErrInvalidScript, len(s.Keys))
+	}
+	if s.Required < 1 || s.Required > len(s.Keys) {
+		return fmt.Errorf("%w: %d of %d signatures required", ErrInvalidScript, s.Required, len(s.Keys))
+	}
+	for i, k := range s.Keys {
+		if _, ok := keyTypeNames[k.KeyType]; !ok || k.KeyType == KeyScriptHash {
+			return fmt.Errorf("%w: key %d of type %s", ErrInvalidScript, i, k.KeyType)
+		}
+		if len(k.PubKey) == 0 || len(k.PubKey) > 255 {
+			return fmt.Errorf("%w: key %d of %d bytes", ErrInvalidScript, i, len(k.PubKey))
+		}
+	}
+
+	return nil
+}
+
+// Serialize encodes the script as the number of required signatures,
+// the number of keys, and the type, length and bytes of every key
+func (s *RedeemScript) Serialize() []byte {
+	d := []byte{byte(s.Required), byte(len(s.Keys))}
+	for _, k := range s.Keys {
+		d = append(d, byte(k.KeyType), byte(len(k.PubKey)))
+		d = append(d, k.PubKey...)
+	}
+
+	return d
+}
+
+// ParseRedeemScript decodes a script Serialize encoded
+func ParseRedeemScript(d []byte) (*RedeemScript, error) {
+	if len(d) < 2 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidScript, len(d))
+	}
+	s := &RedeemScript{Required: int(d[0]), Keys: make([]ScriptKey, d[1])}
+
+	d = d[2:]
+	for i := range s.Keys {
+		if len(d) < 2 || len(d) < 2+int(d[1]) {
+			return nil, fmt.Errorf("%w: key %d is cut", ErrInvalidScript, i)
+		}
+		s.Keys[i] = ScriptKey{KeyType(d[0]), d[2 : 2+int(d[1])]}
+		d = d[2+int(d[1]):]
+	}
+	if len(d) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrInvalidScript, len(d))
+	}
+
+	err := s.check()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Hash returns the hash outputs paying the script lock to
+func (s *RedeemScript) Hash() []byte {
+	return HashPublicKey(s.Serialize())
+}
+
+// Address returns the pay-to-script-hash address of the script
+func (s *RedeemScript) Address(format AddressFormat, params *chaincfg.Params) string {
+	return NewAddress(KeyScriptHash, s.Hash(), format, params).String()
+}
+
+// ScriptSignature the signature of one of the keys of a redeem script
+type ScriptSignature struct {
+	Index     int
+	Signature []byte
+}
+
+// Sign signs a hash with the wallets holding keys of the script, in the
+// order of the keys, until enough signatures are made. The signatures
+// are encoded as the index of the key and the signature, for each.
+func (s *RedeemScript) Sign(hash []byte, wallets []*Wallet) ([]byte, error) {
+	var d []byte
+	signed := 0
+	for i, k := range s.Keys {
+		if signed == s.Required {
+			break
+		}
+		for _, w := range wallets {
+			if w.KeyType != k.KeyType || !bytes.Equal(w.PublicKey, k.PubKey) {
+				continue
+			}
+			sig, err := w.Sign(hash)
+			if err != nil {
+				return nil, err
+			}
+			d = append(d, byte(i))
+			d = append(d, sig...)
+			signed++
+			break
+		}
+	}
+	if signed < s.Required {
+		return nil, fmt.Errorf("%d of %d required signatures: %w", signed, s.Required, ErrNotFound)
+	}
+
+	return d, nil
+}
+
+// Signatures decodes the signatures Sign encoded, they must be exactly
+// the required ones of distinct keys in the order of the keys
+func (s *RedeemScript) Signatures(d []byte) ([]ScriptSignature, error) {
+	if len(d) != s.Required*(1+SignatureLen) {
+		return nil, fmt.Errorf("%w: signatures of %d bytes", ErrInvalidScript, len(d))
+	}
+
+	sigs := make([]ScriptSignature, s.Required)
+	for i := range sigs {
+		index := int(d[0])
+		if index >= len(s.Keys) || (i > 0 && index <= sigs[i-1].Index) {
+			return nil, fmt.Errorf("%w: signature %d of key %d", ErrInvalidScript, i, index)
+		}
+		sigs[i] = ScriptSignature{index, d[1 : 1+SignatureLen]}
+		d = d[1+SignatureLen:]
+	}
+
+	return sigs, nil
+}
+
+// ScriptSpender spends the outputs paying a redeem script with the
+// wallets of its keys
+type ScriptSpender struct {
+	Script  *RedeemScript
+	Wallets []*Wallet
+}
+
+// Lock implements Spender
+func (s ScriptSpender) Lock() (KeyType, []byte) {
+	return KeyScriptHash, s.Script.Hash()
+}
+
+// Unlock implements Spender, the input reveals the script
+func (s ScriptSpender) Unlock() []byte {
+	return s.Script.Serialize()
+}
+
+// Sign implements Spender
+func (s ScriptSpender) Sign(hash []byte) ([]byte, error) {
+	return s.Script.Sign(hash, s.Wallets)
+}
@@ -0,0 +1,92 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+func TestRedeemScript(t *testing.T) {
+	var wallets []*Wallet
+	var keys []ScriptKey
+	for _, keyType := range []KeyType{KeyP256, KeySecp256k1, KeySchnorr} {
+		w, err := NewWalletWithKeyType(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wallets = append(wallets, w)
+		keys = append(keys, ScriptKey{w.KeyType, w.PublicKey})
+	}
+
+	script, err := NewMultiSigScript(2, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseRedeemScript(script.Serialize())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(parsed.Hash(), script.Hash()) || parsed.Required != 2 || len(parsed.Keys) != 3 {
+		t.Errorf("parsed %+v", parsed)
+	}
+
+	a, err := ParseAddress(script.Address(AddressBech32, &chaincfg.RegTestParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.KeyType != KeyScriptHash || !bytes.Equal(a.PubKeyHash, script.Hash()) {
+		t.Errorf("script address parsed to %+v", a)
+	}
+
+	// the first two keys whose wallets are given sign
+	hash := sha256.Sum256([]byte("data"))
+	d, err := script.Sign(hash[:], wallets[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigs, err := script.Signatures(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, sig := range sigs {
+		k := script.Keys[sig.Index]
+		if sig.Index != i+1 {
+			t.Errorf("signature %d of key %d", i, sig.Index)
+		}
+		if err := VerifySignature(k.KeyType, k.PubKey, hash[:], sig.Signature); err != nil {
+			t.Errorf("signature %d: %v", i, err)
+		}
+	}
+	if _, err := script.Sign(hash[:], wallets[:1]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("signed with one wallet: %v", err)
+	}
+
+	// signatures of the same key twice, or out of order
+	twice := append(append([]byte{}, d[:1+SignatureLen]...), d[:1+SignatureLen]...)
+	swapped := append(append([]byte{}, d[1+SignatureLen:]...), d[:1+SignatureLen]...)
+	for _, bad := range [][]byte{nil, d[:len(d)-1], twice, swapped} {
+		if _, err := script.Signatures(bad); !errors.Is(err, ErrInvalidScript) {
+			t.Errorf("signatures %x: %v", bad, err)
+		}
+	}
+
+	for _, bad := range []*RedeemScript{
+		{0, keys},
+		{4, keys},
+		{1, nil},
+		{1, []ScriptKey{{KeyScriptHash, script.Hash()}}},
+	} {
+		if _, err := NewMultiSigScript(bad.Required, bad.Keys); !errors.Is(err, ErrInvalidScript) {
+			t.Errorf("script %+v: %v", bad, err)
+		}
+	}
+	encoded := script.Serialize()
+	for _, bad := range [][]byte{nil, encoded[:len(encoded)-1], append(encoded, 0)} {
+		if _, err := ParseRedeemScript(bad); !errors.Is(err, ErrInvalidScript) {
+			t.Errorf("parse %x: %v", bad, err)
+		}
+	}
+}
@@ -1,10 +1,15 @@
-package main
+package wallet
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"math/big"
 )
 
+// ErrInvalidBase58 a string has a character out of the Base58 alphabet
+var ErrInvalidBase58 = errors.New("invalid base58 character")
+
 var b58Alphabet = []byte("123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz")
 
 // Base58Encode base58 is a algorithm similar
@@ -24,7 +29,7 @@ func Base58Encode(d []byte) []byte {
 	}
 
 	ReverseBytes(result)
-	for b := range d {
+	for _, b := range d {
 		if b == 0x00 {
 			result = append([]byte{b58Alphabet[0]}, result...)
 		} else {
@@ -35,20 +40,26 @@ func Base58Encode(d []byte) []byte {
 	return result
 }
 
-// Base58Decode decode data encode by Base58Encode
-func Base58Decode(d []byte) []byte {
+// Base58Decode decode data encode by Base58Encode, each leading '1'
+// is a zero byte
+func Base58Decode(d []byte) ([]byte, error) {
 	result := big.NewInt(0)
 	zeroBytes := 0
 
-	for b := range d {
-		if b == 0x00 {
+	for _, b := range d {
+		if b == b58Alphabet[0] {
 			zeroBytes++
+		} else {
+			break
 		}
 	}
 
 	payload := d[zeroBytes:]
-	for _, b := range payload {
+	for i, b := range payload {
 		charIndex := bytes.IndexByte(b58Alphabet, b)
+		if charIndex < 0 {
+			return nil, fmt.Errorf("%w: %q at %d", ErrInvalidBase58, b, zeroBytes+i)
+		}
 		result.Mul(result, big.NewInt(58))
 		result.Add(result, big.NewInt(int64(charIndex)))
 	}
@@ -56,12 +67,7 @@ func Base58Decode(d []byte) []byte {
 	decoded := result.Bytes()
 	decoded = append(bytes.Repeat([]byte{byte(0x00)}, zeroBytes), decoded...)
 
-	return decoded
-}
-
-// IntToHex conver int to bytes
-func IntToHex(i int64) []byte {
-	return big.NewInt(i).Bytes()
+	return decoded, nil
 }
 
 // ReverseBytes reverses a byte array
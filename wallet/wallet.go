@@ -0,0 +1,184 @@
+// Package wallet manages the key pairs and addresses of a chain's users
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"math/big"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// AddressFormat the encoding of the address of a wallet
+type AddressFormat byte
+
+const (
+	// AddressBase58 Base58Check, the format of the first wallets
+	AddressBase58 AddressFormat = iota
+	// AddressBech32 Bech32 or Bech32m, depending on the key type
+	AddressBech32
+)
+
+var addressFormatNames = map[AddressFormat]string{
+	AddressBase58: "base58",
+	AddressBech32: "bech32",
+}
+
+func (f AddressFormat) String() string {
+	name, ok := addressFormatNames[f]
+	if !ok {
+		return fmt.Sprintf("AddressFormat(%d)", byte(f))
+	}
+
+	return name
+}
+
+// ParseAddressFormat returns the address format of a name String returns
+func ParseAddressFormat(name string) (AddressFormat, error) {
+	for f, n := range addressFormatNames {
+		if n == name {
+			return f, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown address format: %s", name)
+}
+
+// Wallet the wallet of block chain
+type Wallet struct {
+	KeyType KeyType
+	// PrivateKey the 32-byte scalar of the key
+	PrivateKey []byte
+	PublicKey  []byte
+	// Format the encoding Address uses
+	Format AddressFormat
+}
+
+// NewWallet create a new wallet with a P-256 key
+func NewWallet() (*Wallet, error) {
+	return NewWalletWithKeyType(KeyP256)
+}
+
+// NewWalletWithKeyType create a new wallet with a key of the given type
+// and a Base58Check address
+func NewWalletWithKeyType(t KeyType) (*Wallet, error) {
+	privateKey, publicKey, err := newKeyPair(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wallet{t, privateKey, publicKey, AddressBase58}, nil
+}
+
+// Spender unlocks the outputs paying an address: a wallet, or a redeem
+// script and the wallets of its keys
+type Spender interface {
+	// Lock returns the key type and hash of the outputs it spends
+	Lock() (KeyType, []byte)
+	// Unlock returns what the spending input reveals to match the hash:
+	// the public key or the redeem script
+	Unlock() []byte
+	// Sign returns the signatures of the spending input signing hash
+	Sign(hash []byte) ([]byte, error)
+}
+
+// Lock implements Spender
+func (w *Wallet) Lock() (KeyType, []byte) {
+	return w.KeyType, HashPublicKey(w.PublicKey)
+}
+
+// Unlock implements Spender, the input reveals the public key
+func (w *Wallet) Unlock() []byte {
+	return w.PublicKey
+}
+
+// Sign signs a hash with the key of the wallet
+func (w *Wallet) Sign(hash []byte) ([]byte, error) {
+	return sign(w.KeyType, w.PrivateKey, hash)
+}
+
+// checkKey makes sure the public key is the one of the private key,
+// P-256 keys in either of the forms ParsePubKey accepts
+func (w *Wallet) checkKey() error {
+	point, pubKey, err := publicKey(w.KeyType, w.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	if point == nil {
+		if !bytes.Equal(pubKey, w.PublicKey) {
+			return fmt.Errorf("%w: it does not match the private key", ErrInvalidPubKey)
+		}
+		return nil
+	}
+
+	pub, err := ParsePubKey(w.PublicKey)
+	if err != nil {
+		return err
+	}
+	if pub.X.Cmp(point.X) != 0 || pub.Y.Cmp(point.Y) != 0 {
+		return fmt.Errorf("%w: it does not match the private key", ErrInvalidPubKey)
+	}
+
+	return nil
+}
+
+// Address get address of a wallet in its format, which tells the key type
+func (w Wallet) Address(params *chaincfg.Params) []byte {
+	return []byte(NewAddress(w.KeyType, HashPublicKey(w.PublicKey), w.Format, params).String())
+}
+
+// walletData the persisted form of a wallet, version 0 wallets have
+// no key type and are P-256 ones
+type walletData struct {
+	D         []byte
+	PublicKey []byte
+	KeyType   KeyType
+	Format    AddressFormat
+}
+
+// GobEncode implements gob.GobEncoder
+func (w Wallet) GobEncode() ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(walletData{w.PrivateKey, w.PublicKey, w.KeyType, w.Format})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder
+func (w *Wallet) GobDecode(d []byte) error {
+	var data walletData
+
+	err := gob.NewDecoder(bytes.NewReader(d)).Decode(&data)
+	if err != nil {
+		return err
+	}
+	if len(data.D) > 32 {
+		return fmt.Errorf("private key of %d bytes", len(data.D))
+	}
+
+	w.KeyType = data.KeyType
+	w.PrivateKey = new(big.Int).SetBytes(data.D).FillBytes(make([]byte, 32))
+	w.PublicKey = data.PublicKey
+	w.Format = data.Format
+
+	return nil
+}
+
+// HashPublicKey ...
+func HashPublicKey(pk []byte) []byte {
+	publicSHA256 := sha256.Sum256(pk)
+
+	RIPEMD160Hasher := ripemd160.New()
+	// writing to a hash never fails
+	RIPEMD160Hasher.Write(publicSHA256[:])
+
+	return RIPEMD160Hasher.Sum(nil)
+}
@@ -0,0 +1,344 @@
+package wallet
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/MikasaAkerman/blockchain-go/chaincfg"
+)
+
+// ErrNotFound there is no wallet for the address
+var ErrNotFound = errors.New("wallet not found")
+
+// walletFileVersion the version of the wallet file format:
+//
+//	0 public keys are the unpadded X || Y concatenation. The first files
+//	  hold the crypto/ecdsa keys of the wallets, see legacyWallet, the
+//	  later ones the walletData each wallet encodes itself to.
+//	1 new public keys are compressed, version 0 keys are kept as they
+//	  are so the addresses hashing them stay the same
+//	2 wallets record their key type, the older ones are P-256 wallets
+//	3 wallets record their address format, the older ones use Base58Check
+//	4 the file holds redeem scripts
+//	5 the file holds the outputs locked from spending
+const walletFileVersion = 5
+
+// Wallets ...
+type Wallets struct {
+	Wallets map[string]*Wallet
+	// Scripts the redeem scripts by their pay-to-script-hash address
+	Scripts map[string]*RedeemScript
+	// Locked the outputs coin selection leaves alone, by outpoint
+	Locked map[string]bool
+	// Version the format of the wallet file, see walletFileVersion
+	Version int
+	mu      *sync.RWMutex
+	file    string
+	params  *chaincfg.Params
+}
+
+// NewWallets loads the wallets of a network from the wallet file,
+// which may not exist yet
+func NewWallets(file string, params *chaincfg.Params) (*Wallets, error) {
+	wallets := Wallets{}
+	wallets.mu = new(sync.RWMutex)
+	wallets.Wallets = make(map[string]*Wallet)
+	wallets.Scripts = make(map[string]*RedeemScript)
+	wallets.Locked = make(map[string]bool)
+	wallets.file = file
+	wallets.params = params
+	wallets.Version = walletFileVersion
+
+	err := wallets.LoadFromFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return &wallets, nil
+}
+
+// CreateWallet creates a wallet with a key of the given type and an
+// address in the given format
+func (ws *Wallets) CreateWallet(t KeyType, format AddressFormat) (string, error) {
+	wallet, err := NewWalletWithKeyType(t)
+	if err != nil {
+		return "", err
+	}
+	wallet.Format = format
+	address := fmt.Sprintf("%s", wallet.Address(ws.params))
+
+	ws.mu.Lock()
+	ws.Wallets[address] = wallet
+	ws.mu.Unlock()
+
+	return address, nil
+}
+
+// CreateMultiSig adds the script requiring signatures of required of the
+// wallets of the addresses, and returns its address in the given format
+func (ws *Wallets) CreateMultiSig(required int, addresses []string, format AddressFormat) (string, error) {
+	keys := make([]ScriptKey, len(addresses))
+	for i, address := range addresses {
+		w, err := ws.Wallet(address)
+		if err != nil {
+			return "", err
+		}
+		keys[i] = ScriptKey{w.KeyType, w.PublicKey}
+	}
+
+	script, err := NewMultiSigScript(required, keys)
+	if err != nil {
+		return "", err
+	}
+	address := script.Address(format, ws.params)
+
+	ws.mu.Lock()
+	ws.Scripts[address] = script
+	ws.mu.Unlock()
+
+	return address, nil
+}
+
+// Addresses ...
+func (ws *Wallets) Addresses() []string {
+	var addresses []string
+
+	ws.mu.RLock()
+	for addr := range ws.Wallets {
+		addresses = append(addresses, addr)
+	}
+	for addr := range ws.Scripts {
+		addresses = append(addresses, addr)
+	}
+	ws.mu.RUnlock()
+
+	return addresses
+}
+
+// Wallet get a wallet from wallets
+func (ws *Wallets) Wallet(address string) (*Wallet, error) {
+	ws.mu.RLock()
+	wallet, ok := ws.Wallets[address]
+	ws.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", address, ErrNotFound)
+	}
+
+	return wallet, nil
+}
+
+// FindWallet returns the wallet whose key of type t hashes to pubKeyHash
+func (ws *Wallets) FindWallet(t KeyType, pubKeyHash []byte) (*Wallet, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	for _, w := range ws.Wallets {
+		if w.KeyType == t && bytes.Equal(HashPublicKey(w.PublicKey), pubKeyHash) {
+			return w, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key hash %x: %w", pubKeyHash, ErrNotFound)
+}
+
+// FindScript returns the redeem script hashing to scriptHash
+func (ws *Wallets) FindScript(scriptHash []byte) (*RedeemScript, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	for _, script := range ws.Scripts {
+		if bytes.Equal(script.Hash(), scriptHash) {
+			return script, nil
+		}
+	}
+
+	return nil, fmt.Errorf("script hash %x: %w", scriptHash, ErrNotFound)
+}
+
+// Spender returns what spends the outputs paying an address: its wallet,
+// or its redeem script with the wallets of the keys the file holds
+func (ws *Wallets) Spender(address string) (Spender, error) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+
+	if w, ok := ws.Wallets[address]; ok {
+		return w, nil
+	}
+	script, ok := ws.Scripts[address]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", address, ErrNotFound)
+	}
+
+	spender := ScriptSpender{Script: script}
+	for _, k := range script.Keys {
+		for _, w := range ws.Wallets {
+			if w.KeyType == k.KeyType && bytes.Equal(w.PublicKey, k.PubKey) {
+				spender.Wallets = append(spender.Wallets, w)
+				break
+			}
+		}
+	}
+
+	return spender, nil
+}
+
+// LockOutput keeps coin selection from spending the output, an outpoint
+// written txid:vout
+func (ws *Wallets) LockOutput(outpoint string) {
+	ws.mu.Lock()
+	ws.Locked[outpoint] = true
+	ws.mu.Unlock()
+}
+
+// UnlockOutput lets coin selection spend the output again
+func (ws *Wallets) UnlockOutput(outpoint string) {
+	ws.mu.Lock()
+	delete(ws.Locked, outpoint)
+	ws.mu.Unlock()
+}
+
+// LockedOutputs returns the outpoints of the locked outputs
+func (ws *Wallets) LockedOutputs() map[string]bool {
+	locked := make(map[string]bool)
+
+	ws.mu.RLock()
+	for op := range ws.Locked {
+		locked[op] = true
+	}
+	ws.mu.RUnlock()
+
+	return locked
+}
+
+// LoadFromFile loads wallets from the file
+func (ws *Wallets) LoadFromFile() error {
+	if _, err := os.Stat(ws.file); os.IsNotExist(err) {
+		return nil
+	}
+
+	fileContent, err := ioutil.ReadFile(ws.file)
+	if err != nil {
+		return err
+	}
+
+	var wallets Wallets
+	decoder := gob.NewDecoder(bytes.NewReader(fileContent))
+
+	err = decoder.Decode(&wallets)
+	if err != nil {
+		legacy, legacyErr := decodeLegacyWallets(fileContent)
+		if legacyErr != nil {
+			return err
+		}
+		wallets = *legacy
+	}
+
+	if wallets.Version > walletFileVersion {
+		return fmt.Errorf("wallet file version %d is newer than %d", wallets.Version, walletFileVersion)
+	}
+	migrated := wallets.Version < walletFileVersion
+	if migrated {
+		err = migrateWallets(&wallets)
+		if err != nil {
+			return err
+		}
+	}
+
+	ws.mu.Lock()
+	ws.Wallets = wallets.Wallets
+	if wallets.Scripts != nil {
+		ws.Scripts = wallets.Scripts
+	}
+	if wallets.Locked != nil {
+		ws.Locked = wallets.Locked
+	}
+	ws.mu.Unlock()
+
+	if migrated {
+		return ws.SaveToFile()
+	}
+
+	return nil
+}
+
+// legacyWallet the layout of the wallets of the first files, which held
+// the crypto/ecdsa key of a P-256 wallet. Only the scalar of the key is
+// decoded, its curve and public point are skipped.
+type legacyWallet struct {
+	PrivateKey struct{ D *big.Int }
+	PublicKey  []byte
+}
+
+// decodeLegacyWallets decodes a file written before wallets encoded
+// themselves, its wallets are version 0 ones
+func decodeLegacyWallets(d []byte) (*Wallets, error) {
+	var legacy struct{ Wallets map[string]*legacyWallet }
+
+	err := gob.NewDecoder(bytes.NewReader(d)).Decode(&legacy)
+	if err != nil {
+		return nil, err
+	}
+
+	wallets := Wallets{Wallets: make(map[string]*Wallet)}
+	for address, lw := range legacy.Wallets {
+		key := lw.PrivateKey.D
+		if key == nil || key.Sign() <= 0 || key.BitLen() > 256 {
+			return nil, fmt.Errorf("migrate wallet %s: invalid private key", address)
+		}
+		wallets.Wallets[address] = &Wallet{KeyP256, key.FillBytes(make([]byte, 32)), lw.PublicKey, AddressBase58}
+	}
+
+	return &wallets, nil
+}
+
+// migrateWallets brings wallets loaded from an older file to the
+// current version, one version at a time
+func migrateWallets(ws *Wallets) error {
+	for ; ws.Version < walletFileVersion; ws.Version++ {
+		switch ws.Version {
+		case 0:
+			// the keys stay in their version 0 form, they must
+			// still parse to the key of the wallet
+			for address, w := range ws.Wallets {
+				err := w.checkKey()
+				if err != nil {
+					return fmt.Errorf("migrate wallet %s: %w", address, err)
+				}
+			}
+		case 1:
+			// a missing key type decodes as KeyP256 already
+		case 2:
+			// and a missing address format as AddressBase58
+		case 3:
+			// older files hold no scripts
+		case 4:
+			// and lock no outputs
+		}
+	}
+
+	return nil
+}
+
+// SaveToFile saves wallets to a file
+func (ws *Wallets) SaveToFile() error {
+	var content bytes.Buffer
+
+	encoder := gob.NewEncoder(&content)
+
+	ws.mu.RLock()
+	err := encoder.Encode(ws)
+	ws.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(ws.file, content.Bytes(), 0644)
+}